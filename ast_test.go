@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"scoop/components"
+	"scoop/semantics"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata with the current output")
+
+// TestAstGolden compares the S-expressions of the sample scripts with testdata/<script>.golden,
+// go test -run TestAstGolden -update rewrites them after an intended change
+func TestAstGolden(t *testing.T) {
+	for _, script := range []string{"file.txt"} {
+		source, err := os.ReadFile(script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		statements, err := components.InitParser(components.InitScanner(string(source)).ScanTokens()).Parse()
+		if err != nil {
+			t.Fatalf("%v: %v", script, err)
+		}
+		got := semantics.InitAbstractSyntaxTreePrinter().PrintProgram(statements)
+
+		golden := filepath.Join("testdata", script+".golden")
+		if *update {
+			if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("%v, run go test -run TestAstGolden -update to create it", err)
+		}
+		if got != string(want) {
+			t.Errorf("%v does not match %v, run go test -run TestAstGolden -update if the change is intended\n got:\n%v\nwant:\n%v", script, golden, got, want)
+		}
+	}
+}
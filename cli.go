@@ -0,0 +1,372 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"os"
+	"scoop/components"
+	"scoop/dap"
+	"scoop/debugger"
+	"scoop/lsp"
+	"scoop/semantics"
+	"strconv"
+	"strings"
+)
+
+// Version is reported by --version, release builds set it with -ldflags "-X main.Version=..."
+var Version string = "0.1.0"
+
+type command struct {
+	name    string
+	usage   string
+	summary string
+	// run receives the arguments after the command name and returns the exit status
+	run func(s *Scoop, args []string) int
+}
+
+var commands = []command{
+	{"run", "run [script] [args...]", "run a script, args are visible to it as the 'args' global", (*Scoop).runCommand},
+	{"repl", "repl", "start the interactive prompt", (*Scoop).replCommand},
+	{"tokens", "tokens [script]", "print the tokens the scanner produces", (*Scoop).tokensCommand},
+	{"check", "check [script]...", "scan, parse and resolve without running", (*Scoop).checkCommand},
+	{"ast", "ast [--format=sexpr|json] [script]", "print the syntax tree", (*Scoop).astCommand},
+	{"graph", "graph [--scopes] [script]", "print the syntax tree as a Graphviz DOT graph", (*Scoop).graphCommand},
+	{"fmt", "fmt [--check|--write] [script]...", "format scripts", (*Scoop).fmtCommand},
+	{"lint", "lint [script]...", "warn about legal but suspicious code", (*Scoop).lintCommand},
+	{"doc", "doc [--format=markdown|html] [--out=FILE] [path]...", "document the top level declarations of scripts and directories", (*Scoop).docCommand},
+	{"mod", "mod tidy|vendor [dir]", "lock the dependencies of the package in scoop.mod, vendor also copies them to vendor/", (*Scoop).modCommand},
+	{"lsp", "lsp", "start a language server on stdin/stdout", (*Scoop).lspCommand},
+	{"debug", "debug [--break=LINE,...] [script] [args...]", "run a script in the step debugger", (*Scoop).debugCommand},
+	{"dap", "dap [--port=PORT]", "start a Debug Adapter Protocol server on stdin/stdout or a local port", (*Scoop).dapCommand},
+}
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintln(out, "Usage : scoop [flags] [command] [arguments]")
+	fmt.Fprintln(out, "        scoop [flags] [script] [args...]")
+	fmt.Fprintln(out, "        scoop [flags] -e 'code' [args...]")
+	fmt.Fprintln(out, "\nCommands:")
+	for _, command := range commands {
+		fmt.Fprintf(out, "  %-46v %v\n", command.usage, command.summary)
+	}
+	fmt.Fprintln(out, "\nFlags:")
+	flag.PrintDefaults()
+}
+
+func main() {
+	code := flag.String("e", "", "run the given code instead of a script")
+	quiet := flag.Bool("quiet", false, "hide all diagnostic logging")
+	verbose := flag.Bool("verbose", false, "log what the interpreter is doing")
+	version := flag.Bool("version", false, "print the version and exit")
+	maxSteps := flag.Int("max-steps", 0, "stop scripts after this many statements and expressions (0 = no limit)")
+	maxDepth := flag.Int("max-depth", 0, "stop scripts nesting blocks deeper than this (0 = no limit)")
+	maxString := flag.Int("max-string", 0, "stop scripts building strings longer than this many bytes (0 = no limit)")
+	rawPrint := flag.Bool("raw-print", false, "print values without the '>> ' prefix, for output read by other programs")
+	timeout := flag.Duration("timeout", 0, "stop scripts running longer than this, e.g. 500ms or 2s (0 = no limit)")
+	flag.Usage = usage
+	flag.Parse()
+
+	if *version {
+		fmt.Println("scoop " + Version)
+		return
+	}
+	Verbose = *verbose && !*quiet
+	if *quiet {
+		log.SetOutput(io.Discard)
+	}
+
+	runner := Scoop{}
+	highlighter = components.InitHighlighter(colorEnabled(os.Stdout))
+	interpreter.SetValueStyler(highlighter.Value)
+	interpreter.SetRawPrint(*rawPrint)
+	interpreter.SetLimits(semantics.Limits{MaxSteps: *maxSteps, MaxDepth: *maxDepth, MaxStringLength: *maxString, Timeout: *timeout})
+	modules = components.InitScriptLoader(components.SearchPath(os.Getenv("SCOOP_PATH")))
+	interpreter.SetModuleLoader(modules)
+	debugf("Starting Scoop Interpreter...")
+
+	args := flag.Args()
+	if isFlagSet("e") {
+		os.Exit(runner.evalCommand(*code, args))
+	}
+	if len(args) == 0 {
+		os.Exit(runner.replCommand(args))
+	}
+
+	for _, command := range commands {
+		if command.name == args[0] {
+			os.Exit(command.run(&runner, args[1:]))
+		}
+	}
+	// scoop file.txt is short for scoop run file.txt
+	os.Exit(runner.runCommand(args))
+}
+
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// setArgs hands the extra command line arguments to the script as the 'args' global
+func (s *Scoop) setArgs(args []string) {
+	elements := []interface{}{}
+	for _, arg := range args {
+		elements = append(elements, arg)
+	}
+	interpreter.DefineGlobal("args", semantics.InitList(elements))
+}
+
+func (s *Scoop) runCommand(args []string) int {
+	if len(args) == 0 {
+		log.Println("Usage : scoop run [script] [args...]")
+		return 64
+	}
+	s.setArgs(args[1:])
+	if strings.HasSuffix(args[0], ".json") {
+		return s.runAstFile(args[0])
+	}
+	return s.runFile(args[0])
+}
+
+func (s *Scoop) evalCommand(code string, args []string) int {
+	s.setArgs(args)
+	s.run(code)
+	return s.exitStatus()
+}
+
+func (s *Scoop) replCommand(args []string) int {
+	s.setArgs(args)
+	s.runPrompt()
+	return 0
+}
+
+func (s *Scoop) tokensCommand(args []string) int {
+	if len(args) != 1 {
+		log.Println("Usage : scoop tokens [script]")
+		return 64
+	}
+	file, err := os.Open(args[0])
+	if err != nil {
+		log.Println(err)
+		return 66
+	}
+	defer file.Close()
+
+	scanner := components.InitStreamScanner(file)
+	for token := scanner.Next(); ; token = scanner.Next() {
+		line := fmt.Sprintf("%4d:%-3d %-14v %v", token.Line, token.Column, token.TokenType, token.Lexeme)
+		if token.Literal != nil {
+			line += fmt.Sprintf("  %v", token.Literal)
+		}
+		fmt.Println(line)
+		if token.TokenType == semantics.EOF {
+			return 0
+		}
+	}
+}
+
+func (s *Scoop) checkCommand(args []string) int {
+	if len(args) == 0 {
+		log.Println("Usage : scoop check [script]...")
+		return 64
+	}
+	for _, path := range args {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			log.Println(err)
+			return 66
+		}
+		setScriptPath(path)
+		if strings.HasSuffix(path, ".json") {
+			statements, err := semantics.InitJSONDecoder().Decode(bytes)
+			if err != nil {
+				log.Println(err)
+				return 65
+			}
+			s.resolve(func() string { return "" }, statements)
+			continue
+		}
+		source := string(bytes)
+		s.resolve(func() string { return source }, s.parse(source))
+	}
+	return s.exitStatus()
+}
+
+func (s *Scoop) astCommand(args []string) int {
+	astFlags := flag.NewFlagSet("ast", flag.ExitOnError)
+	format := astFlags.String("format", "sexpr", "output format: sexpr or json")
+	astFlags.Parse(args)
+	if astFlags.NArg() != 1 {
+		log.Println("Usage : scoop ast [--format=sexpr|json] [script]")
+		return 64
+	}
+	s.printAst(astFlags.Arg(0), *format)
+	return 0
+}
+
+func (s *Scoop) graphCommand(args []string) int {
+	graphFlags := flag.NewFlagSet("graph", flag.ExitOnError)
+	scopes := graphFlags.Bool("scopes", false, "overlay lexical scopes and variable resolution")
+	graphFlags.Parse(args)
+	if graphFlags.NArg() != 1 {
+		log.Println("Usage : scoop graph [--scopes] [script]")
+		return 64
+	}
+	s.printGraph(graphFlags.Arg(0), *scopes)
+	return 0
+}
+
+func (s *Scoop) fmtCommand(args []string) int {
+	fmtFlags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := fmtFlags.Bool("check", false, "list files that are not formatted and exit with status 1")
+	write := fmtFlags.Bool("write", false, "write the result back to the files")
+	fmtFlags.Parse(args)
+	if fmtFlags.NArg() == 0 {
+		log.Println("Usage : scoop fmt [--check|--write] [script]...")
+		return 64
+	}
+	if !s.formatFiles(fmtFlags.Args(), *check, *write) {
+		return 1
+	}
+	return 0
+}
+
+func (s *Scoop) lintCommand(args []string) int {
+	if len(args) == 0 {
+		log.Println("Usage : scoop lint [script]...")
+		return 64
+	}
+	status := 0
+	for _, path := range args {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			log.Println(err)
+			return 66
+		}
+
+		source := string(bytes)
+		warnings, err := components.Lint(source)
+		if err != nil {
+			s.parse(source)
+			return s.exitStatus()
+		}
+		for _, warning := range warnings {
+			fmt.Println(path + ": " + highlighter.Warning(warning.String()))
+			printSnippet(source, warning.Token.Line)
+			status = 1
+		}
+	}
+	return status
+}
+
+func (s *Scoop) docCommand(args []string) int {
+	docFlags := flag.NewFlagSet("doc", flag.ExitOnError)
+	format := docFlags.String("format", "markdown", "output format: markdown or html")
+	out := docFlags.String("out", "", "write the documentation to this file instead of stdout")
+	docFlags.Parse(args)
+	if docFlags.NArg() == 0 || (*format != "markdown" && *format != "html") {
+		log.Println("Usage : scoop doc [--format=markdown|html] [--out=FILE] [path]...")
+		return 64
+	}
+	return s.writeDocs(docFlags.Args(), *format, *out)
+}
+
+func (s *Scoop) modCommand(args []string) int {
+	if len(args) == 0 || len(args) > 2 || (args[0] != "tidy" && args[0] != "vendor") {
+		log.Println("Usage : scoop mod tidy|vendor [dir]")
+		return 64
+	}
+	dir := "."
+	if len(args) == 2 {
+		dir = args[1]
+	}
+	return s.lockDependencies(dir, args[0] == "vendor")
+}
+
+func (s *Scoop) lspCommand(args []string) int {
+	if err := lsp.InitServer(os.Stdin, os.Stdout, Version).Serve(); err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
+
+func (s *Scoop) debugCommand(args []string) int {
+	debugFlags := flag.NewFlagSet("debug", flag.ExitOnError)
+	breakpoints := debugFlags.String("break", "", "comma separated lines to stop at")
+	debugFlags.Parse(args)
+	if debugFlags.NArg() == 0 {
+		log.Println("Usage : scoop debug [--break=LINE,...] [script] [args...]")
+		return 64
+	}
+
+	path := debugFlags.Arg(0)
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		log.Println(err)
+		return 66
+	}
+	source := string(bytes)
+	setScriptPath(path)
+
+	debug := debugger.InitDebugger(&interpreter, source, os.Stdin, os.Stdout)
+	for _, line := range strings.Split(*breakpoints, ",") {
+		if line = strings.TrimSpace(line); line != "" {
+			number, err := strconv.Atoi(line)
+			if err != nil {
+				log.Printf("Invalid breakpoint '%v'", line)
+				return 64
+			}
+			debug.SetBreakpoint(number)
+		}
+	}
+	interpreter.SetStepHook(debug.Hook)
+	fmt.Println("Debugging " + path + ", type help for the list of commands")
+
+	s.setArgs(debugFlags.Args()[1:])
+	s.run(source)
+	return s.exitStatus()
+}
+
+func (s *Scoop) dapCommand(args []string) int {
+	dapFlags := flag.NewFlagSet("dap", flag.ExitOnError)
+	port := dapFlags.Int("port", 0, "listen on this port of 127.0.0.1 instead of using stdin/stdout")
+	dapFlags.Parse(args)
+	if dapFlags.NArg() != 0 {
+		log.Println("Usage : scoop dap [--port=PORT]")
+		return 64
+	}
+
+	var in io.Reader = os.Stdin
+	var out io.Writer = os.Stdout
+	if *port != 0 {
+		listener, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(*port))
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer listener.Close()
+		log.Println("Waiting for a debugger on " + listener.Addr().String())
+		// one client per server, like a debug session over stdio
+		connection, err := listener.Accept()
+		if err != nil {
+			log.Println(err)
+			return 1
+		}
+		defer connection.Close()
+		in, out = connection, connection
+	}
+
+	if err := dap.InitServer(in, out).Serve(); err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
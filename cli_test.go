@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFmtCheck(t *testing.T) {
+	dir := t.TempDir()
+	formatted := filepath.Join(dir, "formatted.scoop")
+	unformatted := filepath.Join(dir, "unformatted.scoop")
+	os.WriteFile(formatted, []byte("var a = 1;\nif (a > 0) {\n  print a;\n}\n"), 0644)
+	os.WriteFile(unformatted, []byte("var a=1;\nif(a>0){print a;}\n"), 0644)
+
+	s := &Scoop{}
+	if code := s.fmtCommand([]string{"--check", formatted}); code != 0 {
+		t.Errorf("want --check to pass on a formatted script, got exit status %v", code)
+	}
+	if code := s.fmtCommand([]string{"--check", unformatted}); code == 0 {
+		t.Error("want --check to fail on an unformatted script")
+	}
+	if code := s.fmtCommand([]string{"--check", formatted, unformatted}); code == 0 {
+		t.Error("want --check to fail when any of the scripts is unformatted")
+	}
+
+	if code := s.fmtCommand([]string{"--write", unformatted}); code != 0 {
+		t.Fatalf("--write failed with exit status %v", code)
+	}
+	if code := s.fmtCommand([]string{"--check", unformatted}); code != 0 {
+		t.Errorf("want --check to pass after --write, got exit status %v", code)
+	}
+}
@@ -0,0 +1,146 @@
+package components
+
+import (
+	"fmt"
+	"html"
+	"scoop/semantics"
+	"strings"
+)
+
+// Declaration is one documented top level var or const of a script
+type Declaration struct {
+	Name     string
+	Constant bool
+	Exported bool
+	// Doc is the text of the /// comments above the declaration, without the slashes
+	Doc string
+	// Value is the initialiser rendered by the AbstractSyntaxTreePrinter, empty when there is none
+	Value string
+	Line  int
+}
+
+func (d *Declaration) Keyword() string {
+	keyword := "var"
+	if d.Constant {
+		keyword = "const"
+	}
+	if d.Exported {
+		return "export " + keyword
+	}
+	return keyword
+}
+
+// DocPage holds the declarations of one script, Link is where its source can be found
+// from the generated page
+type DocPage struct {
+	Path         string
+	Link         string
+	Source       string
+	Declarations []*Declaration
+}
+
+// Document parses a script and collects its top level declarations in source order
+func Document(path string, source string) (*DocPage, error) {
+	scanner := InitScanner(source)
+	tokens := scanner.ScanTokens()
+	if errors := scanner.Errors(); len(errors) > 0 {
+		return nil, errors[0]
+	}
+	statements, err := InitParser(tokens).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	printer := semantics.InitAbstractSyntaxTreePrinter()
+	page := &DocPage{Path: path, Link: path, Source: source}
+	for _, statement := range statements {
+		declaration, ok := statement.(*semantics.Var)
+		if !ok {
+			continue
+		}
+		documented := &Declaration{
+			Name:     declaration.Name.Lexeme,
+			Constant: declaration.Constant,
+			Exported: declaration.Exported,
+			Doc:      declaration.DocComment(),
+			Line:     declaration.Start().Line,
+		}
+		if declaration.Initialiser != nil {
+			documented.Value = printer.Print(declaration.Initialiser)
+		}
+		page.Declarations = append(page.Declarations, documented)
+	}
+	return page, nil
+}
+
+// RenderMarkdown writes one section per script, every declaration links to its line
+// in the style code hosts understand (file#L12)
+func RenderMarkdown(pages []*DocPage) string {
+	builder := &strings.Builder{}
+	builder.WriteString("# API reference\n")
+	for _, page := range pages {
+		fmt.Fprintf(builder, "\n## %v\n", page.Path)
+		if len(page.Declarations) == 0 {
+			builder.WriteString("\nNo top level declarations.\n")
+			continue
+		}
+		for _, declaration := range page.Declarations {
+			fmt.Fprintf(builder, "\n### %v `%v`\n\n", declaration.Keyword(), declaration.Name)
+			if declaration.Doc != "" {
+				builder.WriteString(declaration.Doc + "\n\n")
+			}
+			if declaration.Value != "" {
+				fmt.Fprintf(builder, "```\n%v\n```\n\n", declaration.Value)
+			}
+			fmt.Fprintf(builder, "[%v:%v](%v#L%v)\n", page.Path, declaration.Line, page.Link, declaration.Line)
+		}
+	}
+	return builder.String()
+}
+
+// RenderHTML writes a single static page, the source of every script is listed at the
+// end of its section so the line links work without a server
+func RenderHTML(pages []*DocPage) string {
+	builder := &strings.Builder{}
+	builder.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>API reference</title>\n")
+	builder.WriteString("<style>\n")
+	builder.WriteString("body { font-family: sans-serif; max-width: 60em; margin: 2em auto; }\n")
+	builder.WriteString("pre { background: #f4f4f4; padding: .5em; overflow-x: auto; }\n")
+	builder.WriteString(".source span { display: block; }\n")
+	builder.WriteString(".source span:target { background: #fff3b0; }\n")
+	builder.WriteString(".source a { color: #999; display: inline-block; width: 3em; text-decoration: none; }\n")
+	builder.WriteString("</style>\n</head>\n<body>\n<h1>API reference</h1>\n")
+
+	builder.WriteString("<ul>\n")
+	for index, page := range pages {
+		fmt.Fprintf(builder, "<li><a href=\"#file%v\">%v</a></li>\n", index, html.EscapeString(page.Path))
+	}
+	builder.WriteString("</ul>\n")
+
+	for index, page := range pages {
+		anchor := fmt.Sprintf("file%v", index)
+		fmt.Fprintf(builder, "<h2 id=\"%v\">%v</h2>\n", anchor, html.EscapeString(page.Path))
+		if len(page.Declarations) == 0 {
+			builder.WriteString("<p>No top level declarations.</p>\n")
+		}
+		for _, declaration := range page.Declarations {
+			fmt.Fprintf(builder, "<h3>%v <code>%v</code></h3>\n", declaration.Keyword(), html.EscapeString(declaration.Name))
+			if declaration.Doc != "" {
+				fmt.Fprintf(builder, "<p>%v</p>\n", strings.ReplaceAll(html.EscapeString(declaration.Doc), "\n", "<br>\n"))
+			}
+			if declaration.Value != "" {
+				fmt.Fprintf(builder, "<pre>%v</pre>\n", html.EscapeString(declaration.Value))
+			}
+			fmt.Fprintf(builder, "<p><a href=\"#%v-L%v\">%v:%v</a></p>\n", anchor, declaration.Line, html.EscapeString(page.Path), declaration.Line)
+		}
+
+		builder.WriteString("<h3>Source</h3>\n<pre class=\"source\">")
+		for number, line := range strings.Split(strings.TrimSuffix(page.Source, "\n"), "\n") {
+			id := fmt.Sprintf("%v-L%v", anchor, number+1)
+			fmt.Fprintf(builder, "<span id=\"%v\"><a href=\"#%v\">%v</a>%v</span>", id, id, number+1, html.EscapeString(line))
+		}
+		builder.WriteString("</pre>\n")
+	}
+	builder.WriteString("</body>\n</html>\n")
+	return builder.String()
+}
@@ -0,0 +1,212 @@
+package components
+
+import (
+	"scoop/semantics"
+	"strings"
+)
+
+// Formatter re-emits a program in the canonical scoop style:
+//
+//   - two space indentation, one statement per line
+//   - opening braces on the same line, "} else {" kept together
+//   - one space around binary operators and after commas, none after unary operators
+//   - comments are kept where they were, trailing comments stay on their line
+//   - runs of blank lines between statements collapse to a single blank line
+//
+// It works over the token stream of a trivia preserving Scanner so comments survive,
+// the program is parsed first and invalid programs are never formatted.
+// Formatting is idempotent, formatting already formatted code returns it unchanged
+type Formatter struct {
+	builder *strings.Builder
+	indent  int
+	// the newline owed after the last token, written once we know the next token
+	// is not a trailing comment that belongs on the same line
+	pendingNewline bool
+	lastLine       int
+	previous       *semantics.Token
+	// the last token that was not a comment, used to tell unary from binary operators
+	previousCode     *semantics.Token
+	previousWasUnary bool
+	parenDepth       int
+}
+
+func InitFormatter() *Formatter {
+	return &Formatter{}
+}
+
+// Format returns the formatted source, or the first parse error found in it
+func (f *Formatter) Format(source string) (string, error) {
+	scanner := InitScanner(source)
+	scanner.PreserveTrivia()
+	tokens := scanner.ScanTokens()
+	if errors := scanner.Errors(); len(errors) > 0 {
+		return "", errors[0]
+	}
+
+	code := []semantics.Token{}
+	for _, token := range tokens {
+		if !token.TokenType.IsComment() {
+			code = append(code, token)
+		}
+	}
+	if _, err := InitParser(code).Parse(); err != nil {
+		return "", err
+	}
+
+	f.builder = &strings.Builder{}
+	f.indent = 0
+	f.pendingNewline = false
+	f.lastLine = 0
+	f.previous = nil
+	f.previousCode = nil
+	f.previousWasUnary = false
+	f.parenDepth = 0
+
+	for i := range tokens {
+		if tokens[i].TokenType == semantics.EOF {
+			break
+		}
+		f.write(tokens[i], f.nextCode(tokens, i))
+	}
+
+	if f.builder.Len() > 0 {
+		f.builder.WriteString("\n")
+	}
+	return f.builder.String(), nil
+}
+
+func (f *Formatter) write(token semantics.Token, next semantics.Token) {
+	startLine := token.Line
+
+	if token.TokenType.IsComment() {
+		if f.previous != nil && startLine == f.lastLine {
+			// trailing comment, stays at the end of its line
+			f.builder.WriteString(" ")
+		} else {
+			f.startLine(startLine, true)
+		}
+		f.builder.WriteString(token.Lexeme)
+		f.finish(token)
+		// code may carry on after a /* */ comment on the same line
+		if !strings.HasPrefix(token.Lexeme, "/*") || next.Line > f.lastLine || next.TokenType == semantics.EOF {
+			f.pendingNewline = true
+		}
+		return
+	}
+
+	if token.TokenType == semantics.RIGHT_BRACE {
+		f.indent--
+		f.pendingNewline = f.previous != nil
+	}
+
+	if f.pendingNewline {
+		f.startLine(startLine, token.TokenType != semantics.RIGHT_BRACE)
+	} else if f.previous != nil && f.spaceBefore(token) {
+		f.builder.WriteString(" ")
+	}
+
+	f.builder.WriteString(token.Lexeme)
+	f.previousWasUnary = f.isUnary(token)
+
+	switch token.TokenType {
+	case semantics.LEFT_PAREN:
+		f.parenDepth++
+	case semantics.RIGHT_PAREN:
+		f.parenDepth--
+	case semantics.LEFT_BRACE:
+		f.indent++
+		f.pendingNewline = true
+	case semantics.SEMICOLON:
+		f.pendingNewline = f.parenDepth == 0
+	case semantics.RIGHT_BRACE:
+		f.pendingNewline = next.TokenType != semantics.ELSE
+	}
+
+	f.finish(token)
+	f.previousCode = f.previous
+}
+
+// startLine ends the current line and indents the next one, keeping a single
+// blank line where the source had one or more between two statements
+func (f *Formatter) startLine(startLine int, allowBlank bool) {
+	if f.previous != nil {
+		f.builder.WriteString("\n")
+		if allowBlank && startLine-f.lastLine > 1 && f.previous.TokenType != semantics.LEFT_BRACE {
+			f.builder.WriteString("\n")
+		}
+	}
+	f.builder.WriteString(strings.Repeat("  ", f.indent))
+	f.pendingNewline = false
+}
+
+func (f *Formatter) finish(token semantics.Token) {
+	f.lastLine = token.Line + strings.Count(token.Lexeme, "\n")
+	f.previous = &token
+}
+
+func (f *Formatter) spaceBefore(token semantics.Token) bool {
+	switch f.previous.TokenType {
+	case semantics.LEFT_PAREN, semantics.DOT, semantics.QUESTION_DOT:
+		return false
+	}
+
+	switch token.TokenType {
+	case semantics.RIGHT_PAREN, semantics.SEMICOLON, semantics.COMMA, semantics.DOT, semantics.QUESTION_DOT:
+		return false
+	case semantics.PLUS_PLUS, semantics.MINUS_MINUS:
+		// x++ hugs its operand
+		if !f.isUnary(token) {
+			return false
+		}
+	case semantics.LEFT_PAREN:
+		// a call, f(x), hugs its callee
+		if f.previous.TokenType == semantics.IDENTIFIER || f.previous.TokenType == semantics.RIGHT_PAREN {
+			return false
+		}
+	}
+
+	// - -a must not become --a, which is a different operator
+	if f.previousWasUnary && f.gluesTo(token) {
+		return true
+	}
+	return !f.previousWasUnary
+}
+
+// gluesTo reports whether writing the token straight after the previous one would scan differently
+func (f *Formatter) gluesTo(token semantics.Token) bool {
+	if f.previous.Lexeme == "" || token.Lexeme == "" {
+		return false
+	}
+	last := f.previous.Lexeme[len(f.previous.Lexeme)-1]
+	first := token.Lexeme[0]
+	return last == first && (first == '-' || first == '+')
+}
+
+// isUnary reports whether an operator token is used as a prefix operator,
+// that is when it does not follow something that ends an operand
+func (f *Formatter) isUnary(token semantics.Token) bool {
+	switch token.TokenType {
+	case semantics.MINUS, semantics.BANG, semantics.TILDE, semantics.PLUS_PLUS, semantics.MINUS_MINUS:
+	default:
+		return false
+	}
+
+	if f.previousCode == nil {
+		return true
+	}
+	switch f.previousCode.TokenType {
+	case semantics.IDENTIFIER, semantics.NUMBER, semantics.STRING, semantics.TRUE, semantics.FALSE,
+		semantics.NIL, semantics.THIS, semantics.SUPER, semantics.RIGHT_PAREN:
+		return false
+	}
+	return true
+}
+
+func (f *Formatter) nextCode(tokens []semantics.Token, index int) semantics.Token {
+	for _, token := range tokens[index+1:] {
+		if !token.TokenType.IsComment() {
+			return token
+		}
+	}
+	return tokens[len(tokens)-1]
+}
@@ -0,0 +1,81 @@
+package components
+
+import (
+	"os"
+	"testing"
+)
+
+// unformatted has comments, nested blocks, an else if chain and doc comments
+const unformatted = `/// the greeting every script prints
+const greeting="hello";  // trailing comment
+/* a block comment
+   over two lines */
+var   count=0;
+{
+var inner = count+1;
+  { var deeper=inner*2; // nested
+  print deeper; }
+}
+if(count==0){print greeting;}else if(count>1)print "many"; else{
+print "one";
+}
+/// documented
+export var total = count ++ ;
+print format("{} {}", greeting, -count), "x".upper();
+`
+
+const formatted = `/// the greeting every script prints
+const greeting = "hello"; // trailing comment
+/* a block comment
+   over two lines */
+var count = 0;
+{
+  var inner = count + 1;
+  {
+    var deeper = inner * 2; // nested
+    print deeper;
+  }
+}
+if (count == 0) {
+  print greeting;
+} else if (count > 1) print "many";
+else {
+  print "one";
+}
+/// documented
+export var total = count++;
+print format("{} {}", greeting, -count), "x".upper();
+`
+
+func TestFormat(t *testing.T) {
+	got, err := InitFormatter().Format(unformatted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != formatted {
+		t.Errorf("got:\n%v\nwant:\n%v", got, formatted)
+	}
+}
+
+// formatting a formatted script must not change it, or fmt --check would never pass
+func TestFormatIsIdempotent(t *testing.T) {
+	file, err := os.ReadFile("../file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, source := range map[string]string{"file.txt": string(file), "unformatted": unformatted, "formatted": formatted} {
+		once, err := InitFormatter().Format(source)
+		if err != nil {
+			t.Errorf("%v: %v", name, err)
+			continue
+		}
+		twice, err := InitFormatter().Format(once)
+		if err != nil {
+			t.Errorf("%v: formatting the result: %v", name, err)
+			continue
+		}
+		if twice != once {
+			t.Errorf("%v: formatting twice changed the result\nonce:\n%v\ntwice:\n%v", name, once, twice)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package components
+
+import (
+	"fmt"
+	"scoop/semantics"
+	"strings"
+)
+
+// ANSI escape sequences used by the highlighter
+const (
+	ansiReset   = "\x1b[0m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiBlue    = "\x1b[34m"
+	ansiMagenta = "\x1b[35m"
+	ansiCyan    = "\x1b[36m"
+	ansiGray    = "\x1b[90m"
+)
+
+// Highlighter colours scoop source code and runtime values.
+// It re-uses the Scanner so the colours always agree with the way the
+// interpreter actually reads the code, when disabled it returns text untouched
+type Highlighter struct {
+	enabled bool
+}
+
+func InitHighlighter(enabled bool) *Highlighter {
+	return &Highlighter{enabled: enabled}
+}
+
+func (h *Highlighter) Enabled() bool {
+	return h.enabled
+}
+
+// Highlight returns the source with every lexeme wrapped in the colour of its TokenType.
+// Whitespace, comments and anything the scanner could not turn into a token is kept as is
+func (h *Highlighter) Highlight(source string) string {
+	if !h.enabled {
+		return source
+	}
+
+	scanner := InitScanner(source)
+	builder := &strings.Builder{}
+	for !scanner.isAtEnd() {
+		scanner.beginToken()
+		count := len(scanner.pending)
+		scanner.scanToken()
+
+		text := source[scanner.start:scanner.current]
+		if len(scanner.pending) > count {
+			builder.WriteString(h.paint(h.tokenColor(scanner, scanner.pending[count]), text))
+		} else if strings.HasPrefix(text, "//") || strings.HasPrefix(text, "/*") {
+			builder.WriteString(h.paint(ansiGray, text))
+		} else {
+			builder.WriteString(text)
+		}
+	}
+	return builder.String()
+}
+
+// Snippet renders the given (1 based) line of source prefixed with its line number,
+// it is used to echo the offending code next to an error
+func (h *Highlighter) Snippet(source string, line int) string {
+	lines := strings.Split(source, "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	gutter := fmt.Sprintf("%4d | ", line)
+	return h.paint(ansiGray, gutter) + h.Highlight(strings.TrimRight(lines[line-1], "\r"))
+}
+
+// Value colours the printed form of a runtime value by its type
+func (h *Highlighter) Value(value interface{}, text string) string {
+	switch value.(type) {
+	case nil:
+		return h.paint(ansiGray, text)
+	case float64:
+		return h.paint(ansiYellow, text)
+	case string:
+		return h.paint(ansiGreen, text)
+	case bool:
+		return h.paint(ansiMagenta, text)
+	}
+	return text
+}
+
+// Error colours an error message
+func (h *Highlighter) Error(text string) string {
+	return h.paint(ansiRed, text)
+}
+
+// Warning colours a warning message
+func (h *Highlighter) Warning(text string) string {
+	return h.paint(ansiYellow, text)
+}
+
+func (h *Highlighter) tokenColor(scanner *Scanner, token semantics.Token) string {
+	switch token.TokenType {
+	case semantics.STRING:
+		return ansiGreen
+	case semantics.NUMBER:
+		return ansiYellow
+	case semantics.IDENTIFIER:
+		return ansiCyan
+	case semantics.COMMENT, semantics.DOC_COMMENT:
+		return ansiGray
+	}
+
+	if _, found := scanner.reservedKeyWordMap[token.Lexeme]; found {
+		return ansiMagenta
+	}
+	return ansiBlue
+}
+
+func (h *Highlighter) paint(color string, text string) string {
+	if !h.enabled || text == "" {
+		return text
+	}
+	return color + text + ansiReset
+}
@@ -0,0 +1,79 @@
+package components
+
+import (
+	"scoop/semantics"
+	"strings"
+)
+
+const ignoreDirective = "scoop:ignore"
+
+// Lint parses the source and returns the linter warnings that are not silenced by a
+// "// scoop:ignore rule..." comment. The comment silences the listed rules (every rule
+// when none is listed) either on the line it ends or, when it sits on a line of its own,
+// on the next line of code
+func Lint(source string) ([]*semantics.Warning, error) {
+	scanner := InitScanner(source)
+	scanner.PreserveTrivia()
+	tokens := scanner.ScanTokens()
+	if errors := scanner.Errors(); len(errors) > 0 {
+		return nil, errors[0]
+	}
+
+	code := []semantics.Token{}
+	ignored := map[int][]string{}
+	pending := []string{}
+	for _, token := range tokens {
+		if !token.TokenType.IsComment() {
+			if len(pending) > 0 {
+				ignored[token.Line] = append(ignored[token.Line], pending...)
+				pending = nil
+			}
+			code = append(code, token)
+			continue
+		}
+		rules, ok := parseIgnoreDirective(token.Lexeme)
+		if !ok {
+			continue
+		}
+		if len(code) > 0 && code[len(code)-1].Line == token.Line {
+			ignored[token.Line] = append(ignored[token.Line], rules...)
+		} else {
+			pending = append(pending, rules...)
+		}
+	}
+
+	statements, err := InitParser(code).Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := []*semantics.Warning{}
+	for _, warning := range semantics.InitLinter().Lint(statements) {
+		if !isIgnored(ignored[warning.Token.Line], warning.Rule) {
+			warnings = append(warnings, warning)
+		}
+	}
+	return warnings, nil
+}
+
+// parseIgnoreDirective reads "// scoop:ignore shadow, unused", an empty rule list means every rule
+func parseIgnoreDirective(comment string) ([]string, bool) {
+	text := strings.TrimSpace(strings.TrimPrefix(comment, "//"))
+	if !strings.HasPrefix(text, ignoreDirective) {
+		return nil, false
+	}
+	rules := strings.Fields(strings.ReplaceAll(strings.TrimPrefix(text, ignoreDirective), ",", " "))
+	if len(rules) == 0 {
+		rules = []string{"*"}
+	}
+	return rules, true
+}
+
+func isIgnored(rules []string, rule string) bool {
+	for _, ignored := range rules {
+		if ignored == rule || ignored == "*" {
+			return true
+		}
+	}
+	return false
+}
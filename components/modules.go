@@ -0,0 +1,135 @@
+package components
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"scoop/manifest"
+	"scoop/semantics"
+	"strings"
+)
+
+// ScriptLoader is the semantics.ModuleLoader for scripts on disk. An import path starting
+// with the name of a dependency in the scoop.mod of the importing script's package is found
+// in that dependency, other paths are looked up next to the importing script first and then
+// in every directory of the search path. Modules are compiled once, the resolver and the
+// interpreter both ask for them
+type ScriptLoader struct {
+	searchPath []string
+	compiled   map[string][]semantics.Statement
+	// modules being compiled, a module importing itself must not be compiled forever
+	compiling map[string]bool
+	// the manifest of the package each directory belongs to, nil outside of packages
+	manifests map[string]*manifest.Manifest
+}
+
+func InitScriptLoader(searchPath []string) *ScriptLoader {
+	return &ScriptLoader{searchPath: searchPath, compiled: map[string][]semantics.Statement{}, compiling: map[string]bool{}, manifests: map[string]*manifest.Manifest{}}
+}
+
+// SearchPath splits a SCOOP_PATH value, the directories are separated like those of PATH
+func SearchPath(value string) []string {
+	directories := []string{}
+	for _, directory := range filepath.SplitList(value) {
+		if directory != "" {
+			directories = append(directories, directory)
+		}
+	}
+	return directories
+}
+
+// Resolve returns the absolute path of the module, modules imported from code that is
+// not in a file are looked up from the working directory
+func (l *ScriptLoader) Resolve(path string, from string) (string, error) {
+	if filepath.IsAbs(path) {
+		return l.existing(path, []string{filepath.Dir(path)})
+	}
+
+	base := "."
+	if from != "" {
+		base = filepath.Dir(from)
+	}
+	m, err := l.manifest(base)
+	if err != nil {
+		return "", err
+	}
+	if m != nil {
+		resolved, found, err := m.Resolve(path)
+		if err != nil {
+			return "", err
+		}
+		if found {
+			if absolute, err := l.existing(resolved, []string{filepath.Dir(resolved)}); err == nil {
+				return absolute, nil
+			}
+			return "", fmt.Errorf("Can't find module '%v', looked in %v.", path, filepath.Dir(resolved))
+		}
+	}
+	directories := append([]string{base}, l.searchPath...)
+	return l.existing(path, directories)
+}
+
+// manifest finds the scoop.mod of the package the directory belongs to
+func (l *ScriptLoader) manifest(directory string) (*manifest.Manifest, error) {
+	if m, found := l.manifests[directory]; found {
+		return m, nil
+	}
+	m, err := manifest.Find(directory)
+	if err != nil {
+		return nil, err
+	}
+	l.manifests[directory] = m
+	return m, nil
+}
+
+func (l *ScriptLoader) existing(path string, directories []string) (string, error) {
+	for _, directory := range directories {
+		candidate := path
+		if !filepath.IsAbs(path) {
+			candidate = filepath.Join(directory, path)
+		}
+		if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+			return filepath.Abs(candidate)
+		}
+	}
+	return "", fmt.Errorf("Can't find module '%v', looked in %v.", path, strings.Join(directories, ", "))
+}
+
+// Load scans and parses the module, any error in it is returned as one
+func (l *ScriptLoader) Load(path string) ([]semantics.Statement, error) {
+	if statements, found := l.compiled[path]; found {
+		return statements, nil
+	}
+	if l.compiling[path] {
+		return nil, fmt.Errorf("Module %v imports itself.", path)
+	}
+	l.compiling[path] = true
+	defer delete(l.compiling, path)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := InitStreamScanner(file)
+	parser := InitStreamParser(scanner)
+	statements, _ := parser.Parse()
+
+	problems := ""
+	for _, err := range scanner.Errors() {
+		if scanErr, ok := err.(*ScanError); ok {
+			problems += fmt.Sprintf("\n[line %v] Error : %v", scanErr.Line, scanErr.Message)
+		}
+	}
+	for _, err := range parser.Errors() {
+		if parseErr, ok := err.(*ParseError); ok {
+			problems += fmt.Sprintf("\n[line %v] Error at '%v' : %v", parseErr.Token.Line, parseErr.Token.Lexeme, parseErr.Message)
+		}
+	}
+	if problems != "" {
+		return nil, fmt.Errorf("Module %v has errors:%v", path, problems)
+	}
+	l.compiled[path] = statements
+	return statements, nil
+}
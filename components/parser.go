@@ -2,8 +2,8 @@ package components
 
 import (
 	"fmt"
-	"log"
 	"scoop/semantics"
+	"strings"
 )
 
 /**
@@ -11,16 +11,33 @@ import (
      * For the parser, in understanding how language is interpreted in terms of PRECEDENCE and ASSOCIATIVITY
      * this are the rules we are following
      *
-     * expression     → equality ;
+     * expression     → conditional ;
+     * conditional    → coalesce ( "?" expression ":" conditional )? ;
+     * coalesce       → bitwiseOr ( "??" bitwiseOr )* ;
+     * bitwiseOr      → bitwiseXor ( "|" bitwiseXor )* ;
+     * bitwiseXor     → bitwiseAnd ( "^" bitwiseAnd )* ;
+     * bitwiseAnd     → equality ( "&" equality )* ;
      * equality       → comparison ( ( "!=" | "==" ) comparison )* ;
-     * comparison     → term ( ( ">" | ">=" | "<" | "<=" ) term )* ;
+     * comparison     → shift ( ( ">" | ">=" | "<" | "<=" ) shift )* ;
+     * shift          → term ( ( "<<" | ">>" ) term )* ;
      * term           → factor ( ( "-" | "+" ) factor )* ;
-     * factor         → unary ( ( "/" | "*" ) unary )* ;
-     * unary          → ( "!" | "-" ) unary
-     *                | primary ;
+     * factor         → unary ( ( "/" | "*" | "%" ) unary )* ;
+     * unary          → ( "!" | "-" | "~" ) unary
+     *                | ( "++" | "--" ) IDENTIFIER
+     *                | power ;
+     * power          → postfix ( "**" unary )? ;
+     * postfix        → call ( "++" | "--" )? ;
+     * call           → primary ( ( "." | "?." ) IDENTIFIER | "(" arguments? ")" )* ;
+     * arguments      → expression ( "," expression )* ;
      * primary        → NUMBER | STRING | "true" | "false" | "nil"
      *                | "(" expression ")" ;
      *
+     * the levels follow C, ** is right associative and binds tighter than a unary
+     * operator on its left, so -2 ** 2 is -(2 ** 2) while 2 ** -1 still parses
+     *
+     * Statements are parsed by recursive descent below, expressions by the Pratt parser in
+     * pratt.go whose binding powers give exactly this grammar
+     *
      *
      *
      *  Parser is written using the RECURSIVE DECENT PARSING which uses a top-down algo because its starts
@@ -43,31 +60,88 @@ import (
      *
 */
 
+// TokenSource hands out tokens one at a time, after the last one it keeps returning EOF
+type TokenSource interface {
+	Next() semantics.Token
+}
+
+// tokenSlice is the TokenSource of tokens that were all scanned up front
+type tokenSlice struct {
+	tokens []semantics.Token
+	next   int
+}
+
+func (t *tokenSlice) Next() semantics.Token {
+	if t.next >= len(t.tokens) {
+		return semantics.Token{TokenType: semantics.EOF}
+	}
+	token := t.tokens[t.next]
+	if token.TokenType != semantics.EOF {
+		t.next++
+	}
+	return token
+}
+
+// Parser pulls tokens from its source as it needs them, it only ever looks at the
+// current token and the one before it so a script is never held whole as tokens
 type Parser struct {
-	tokens  []semantics.Token
-	current int
+	source   TokenSource
+	current  semantics.Token
+	previous semantics.Token
+	errors   []error
+	// the "///" doc comment right above the current token, if there is one
+	doc        string
+	documented bool
 }
 
-type parseError struct {
-	token   semantics.Token
-	message string
+type ParseError struct {
+	Token   semantics.Token
+	Message string
 }
 
-func (e *parseError) Error() string {
-	return fmt.Sprintf("Parse error at %+v: %s", e.token, e.message)
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("Parse error at %+v: %s", e.Token, e.Message)
 }
 
 func (p *Parser) error(token semantics.Token, message string) error {
-	return &parseError{token: token, message: message}
+	return &ParseError{Token: token, Message: message}
 }
 
+// InitParser takes the tokens of a ScanTokens call
 func InitParser(tokens []semantics.Token) *Parser {
-	return &Parser{
-		tokens:  tokens,
-		current: 0,
+	return InitStreamParser(&tokenSlice{tokens: tokens})
+}
+
+// InitStreamParser parses tokens as it pulls them from source, such as a Scanner reading
+// a file. "///" doc comments are set aside and handed to the declaration that follows them
+func InitStreamParser(source TokenSource) *Parser {
+	p := &Parser{source: source}
+	p.pull()
+	return p
+}
+
+// pull makes the next token that is not a comment the current one
+func (p *Parser) pull() {
+	p.doc, p.documented = "", false
+	for {
+		token := p.source.Next()
+		if !token.TokenType.IsComment() {
+			p.current = token
+			p.doc = strings.TrimSuffix(p.doc, "\n")
+			return
+		}
+		if token.TokenType == semantics.DOC_COMMENT {
+			p.doc += docText(token.Lexeme) + "\n"
+			p.documented = true
+		}
 	}
 }
 
+// docText strips the slashes and the space after them from a doc comment
+func docText(lexeme string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(lexeme, "///"), " ")
+}
+
 // func (p *Parser) Parse() (semantics.Expression, error) {
 // 	defer func() {
 // 		if err := recover(); err != nil {
@@ -84,17 +158,6 @@ func InitParser(tokens []semantics.Token) *Parser {
 
 func (p *Parser) Parse() ([]semantics.Statement, error) {
 	statements := []semantics.Statement{}
-	defer func() {
-		if err := recover(); err != nil {
-			if p, ok := err.(*parseError); ok {
-				log.Println(p.Error())
-				return
-				// panic(p.Error())
-				// return // already handled
-			}
-			panic(err.(error).Error())
-		}
-	}()
 	// for statements
 	// for !p.isAtEnd() {
 	// 	statements = append(statements, p.statement())
@@ -103,38 +166,101 @@ func (p *Parser) Parse() ([]semantics.Statement, error) {
 	for !p.isAtEnd() {
 		declaration, err := p.declaration()
 		if err != nil {
-			panic(err.Error())
+			// already recorded, the parser synchronised and carries on
+			continue
 		}
 		statements = append(statements, declaration)
 	}
 
 	// fmt.Print(fmt.Sprintf("\nstatements in Parse : %+v", statements))
 
+	if len(p.errors) > 0 {
+		return statements, p.errors[0]
+	}
 	return statements, nil
 }
 
+// ParseExpression parses the tokens as one expression, debuggers use it to evaluate watches
+func (p *Parser) ParseExpression() (expression semantics.Expression, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if parseErr, ok := recovered.(*ParseError); ok {
+				p.errors = append(p.errors, parseErr)
+				expression, err = nil, parseErr
+				return
+			}
+			panic(recovered)
+		}
+	}()
+
+	expression = p.expression()
+	if !p.isAtEnd() {
+		panic(p.error(p.peek(), "Expect end of expression."))
+	}
+	return expression, nil
+}
+
+// Errors returns every parse error found, Parse only returns the first one
+func (p *Parser) Errors() []error {
+	return p.errors
+}
+
 // variable declaration
-func (p *Parser) declaration() (semantics.Statement, error) {
+func (p *Parser) declaration() (statement semantics.Statement, err error) {
 
-	defer func() error {
-		if err := recover(); err != nil {
-			if _, ok := err.(*parseError); ok {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if parseErr, ok := recovered.(*ParseError); ok {
+				p.errors = append(p.errors, parseErr)
 				p.synchronise()
-				// log.Println(p.Error())
-				return nil
-				// panic(p.Error())
-				// return // already handled
+				statement, err = nil, parseErr
+				return
 			}
-			panic(err.(error).Error())
+			panic(recovered)
 		}
-		return nil
 	}()
 
+	start := p.peek()
+	doc, documented := p.doc, p.documented
+	exported := p.match(semantics.EXPORT)
+	if exported && !p.check(semantics.VAR) && !p.check(semantics.CONST) {
+		panic(p.error(p.peek(), "Expect 'var' or 'const' after 'export'."))
+	}
 	if p.match(semantics.VAR) {
-		return p.varDeclaration(), nil
+		return p.document(doc, documented, p.at(start, p.export(exported, p.varDeclaration()))), nil
+	}
+	if p.match(semantics.CONST) {
+		return p.document(doc, documented, p.at(start, p.export(exported, p.constDeclaration()))), nil
+	}
+	if p.match(semantics.IMPORT) {
+		return p.at(start, p.importDeclaration(p.previous)), nil
+	}
+
+	return p.at(start, p.statement()), nil
+}
+
+// document attaches the doc comment written above a declaration to it
+func (p *Parser) document(doc string, documented bool, statement semantics.Statement) semantics.Statement {
+	if declaration, ok := statement.(semantics.Documented); ok && documented {
+		declaration.SetDocComment(doc)
+	}
+	return statement
+}
+
+// export marks a declaration written after "export"
+func (p *Parser) export(exported bool, statement semantics.Statement) semantics.Statement {
+	if declaration, ok := statement.(*semantics.Var); ok {
+		declaration.Exported = exported
 	}
+	return statement
+}
 
-	return p.statement(), nil
+// at records the token a statement starts with as its position
+func (p *Parser) at(start semantics.Token, statement semantics.Statement) semantics.Statement {
+	span := statement.Start()
+	span.Line = start.Line
+	span.Column = start.Column
+	return statement
 }
 
 func (p *Parser) varDeclaration() semantics.Statement {
@@ -149,13 +275,33 @@ func (p *Parser) varDeclaration() semantics.Statement {
 	return semantics.InitVariableDeclaration(name, initialiser)
 }
 
+// constDeclaration is a varDeclaration that must have an initialiser
+func (p *Parser) constDeclaration() semantics.Statement {
+	name := p.consume(semantics.IDENTIFIER, "Expect constant name.")
+	p.consume(semantics.EQUAL, "Expect '=' after constant name, a constant needs a value.")
+	initialiser := p.expression()
+
+	p.consume(semantics.SEMICOLON, "Expect ';' after constant declaration")
+	return semantics.InitConstantDeclaration(name, initialiser)
+}
+
+// importDeclaration → "import" STRING "as" IDENTIFIER ";" ;
+func (p *Parser) importDeclaration(keyword semantics.Token) semantics.Statement {
+	path := p.consume(semantics.STRING, "Expect the path of the module after 'import'.")
+	p.consume(semantics.AS, "Expect 'as' after the module path.")
+	name := p.consume(semantics.IDENTIFIER, "Expect a name for the module after 'as'.")
+
+	p.consume(semantics.SEMICOLON, "Expect ';' after import.")
+	return semantics.InitImportStatement(keyword, path, name)
+}
+
 func (p *Parser) statement() semantics.Statement {
 	if p.match(semantics.PRINT) {
 		// log.Println("\nInside PRINT STATEMENT")
 		return p.printStatement()
 	}
 	if p.match(semantics.IF) {
-		return p.ifStatement()
+		return p.ifStatement(p.previous)
 	}
 	if p.match(semantics.LEFT_BRACE) {
 		// log.Println("\nInside BLOCK STATEMENT")
@@ -166,18 +312,18 @@ func (p *Parser) statement() semantics.Statement {
 	return p.expressionStatement()
 }
 
-func (p *Parser) ifStatement() semantics.Statement {
+func (p *Parser) ifStatement(keyword semantics.Token) semantics.Statement {
 	var elseBranch semantics.Statement
 	p.consume(semantics.LEFT_PAREN, "Expect '{' after 'if'.")
 	condition := p.expression()
 	p.consume(semantics.RIGHT_PAREN, "Expect '}' after 'if'.")
 
-	thenBranch := p.statement()
+	thenBranch := p.at(p.peek(), p.statement())
 	if p.match(semantics.ELSE) {
-		elseBranch = p.statement()
+		elseBranch = p.at(p.peek(), p.statement())
 	}
 
-	return semantics.InitIFStatement(condition, thenBranch, elseBranch)
+	return semantics.InitIFStatement(keyword, condition, thenBranch, elseBranch)
 }
 
 func (p *Parser) block() []semantics.Statement {
@@ -186,7 +332,7 @@ func (p *Parser) block() []semantics.Statement {
 	for !p.check(semantics.RIGHT_BRACE) && !p.isAtEnd() {
 		declaration, err := p.declaration()
 		if err != nil {
-			panic("something happened when reading block statement")
+			continue
 		}
 		statements = append(statements, declaration)
 	}
@@ -195,11 +341,12 @@ func (p *Parser) block() []semantics.Statement {
 }
 
 func (p *Parser) printStatement() semantics.Statement {
-	expr := p.expression()
-	// log.Printf("inside PRINT RULE [%v]", fmt.Sprint(expr))
-	// fmt.Printf(fmt.Sprintf("\ninside PRINT RULE [%+v]\n", expr))
+	values := []semantics.Expression{p.expression()}
+	for p.match(semantics.COMMA) {
+		values = append(values, p.expression())
+	}
 	p.consume(semantics.SEMICOLON, "Expect ';' after value")
-	return semantics.InitPrintStatement(expr)
+	return semantics.InitPrintStatement(values...)
 }
 
 func (p *Parser) expressionStatement() semantics.Statement {
@@ -209,90 +356,12 @@ func (p *Parser) expressionStatement() semantics.Statement {
 	return semantics.InitExpressionStatement(expr)
 }
 
-func (p *Parser) expression() semantics.Expression {
-	return p.assignment()
-}
-
-func (p *Parser) equality() semantics.Expression {
-	expr := p.comparison()
-
-	for p.match(semantics.BANG_EQUAL, semantics.EQUAL_EQUAL) {
-		operator := p.previous()
-		rightExpr := p.comparison()
-		expr = semantics.InitBinary(expr, operator, rightExpr)
-	}
-	return expr
-}
-
-func (p *Parser) comparison() semantics.Expression {
-	expr := p.term()
-
-	for p.match(semantics.GREATER, semantics.GREATER_EQUAL, semantics.LESS, semantics.LESS_EQUAL) {
-		operator := p.previous()
-		rightExpr := p.term()
-		expr = semantics.InitBinary(expr, operator, rightExpr)
-	}
-	return expr
-}
-
-func (p *Parser) term() semantics.Expression {
-	expr := p.factor()
-
-	for p.match(semantics.PLUS, semantics.MINUS) {
-		operator := p.previous()
-		rightExpr := p.factor()
-		expr = semantics.InitBinary(expr, operator, rightExpr)
-	}
-	return expr
-}
-
-func (p *Parser) factor() semantics.Expression {
-	expr := p.unary()
-
-	for p.match(semantics.SLASH, semantics.STAR) {
-		operator := p.previous()
-		rightExpr := p.unary()
-		expr = semantics.InitBinary(expr, operator, rightExpr)
-	}
-	return expr
-}
-
-func (p *Parser) unary() semantics.Expression {
-
-	if p.match(semantics.BANG, semantics.MINUS) {
-		operator := p.previous()
-		rightExpr := p.unary()
-		return semantics.InitUnary(operator, rightExpr)
+// updateTarget returns the variable ++ or -- applies to, nothing else can be incremented
+func (p *Parser) updateTarget(operator semantics.Token, target semantics.Expression) semantics.Token {
+	if variable, ok := target.(*semantics.Variable); ok {
+		return variable.Name
 	}
-	return p.primary()
-}
-
-func (p *Parser) primary() semantics.Expression {
-	if p.match(semantics.FALSE) {
-		return semantics.InitLiteral(false)
-	}
-	if p.match(semantics.TRUE) {
-		return semantics.InitLiteral(true)
-	}
-	if p.match(semantics.NIL) {
-		return semantics.InitLiteral(nil)
-	}
-
-	if p.match(semantics.NUMBER, semantics.STRING) {
-		return semantics.InitLiteral(p.previous().Literal)
-	}
-
-	if p.match(semantics.IDENTIFIER) {
-		return semantics.InitVariable(p.previous())
-	}
-
-	if p.match(semantics.LEFT_PAREN) {
-		expr := p.expression()
-		p.consume(semantics.RIGHT_PAREN, "Expect ')' after expression")
-		return semantics.InitGrouping(expr)
-	}
-	// panic(p.peek(), )
-	return nil
+	panic(p.error(operator, "Invalid "+operator.Lexeme+" target, expect a variable."))
 }
 
 func (p *Parser) consume(tokenType semantics.TokenType, message string) semantics.Token {
@@ -314,14 +383,11 @@ func (p *Parser) match(types ...semantics.TokenType) bool {
 
 func (p *Parser) advance() semantics.Token {
 	if !p.isAtEnd() {
-		p.current++
+		p.previous = p.current
+		p.pull()
 	}
 
-	return p.previous()
-}
-
-func (p *Parser) previous() semantics.Token {
-	return p.tokens[p.current-1]
+	return p.previous
 }
 
 func (p *Parser) isAtEnd() bool {
@@ -329,7 +395,7 @@ func (p *Parser) isAtEnd() bool {
 }
 
 func (p *Parser) peek() semantics.Token {
-	return p.tokens[p.current]
+	return p.current
 }
 
 func (p *Parser) check(tokenType semantics.TokenType) bool {
@@ -349,54 +415,36 @@ func (p *Parser) check(tokenType semantics.TokenType) bool {
 func (p *Parser) synchronise() {
 	p.advance()
 	for !p.isAtEnd() {
-		if p.previous().TokenType == semantics.SEMICOLON {
+		if p.previous.TokenType == semantics.SEMICOLON {
 			return
 		}
 
+		// Go cases don't fall through, every keyword a statement can start with is one case
 		switch p.peek().TokenType {
-		case semantics.CLASS:
-		case semantics.FUN:
-		case semantics.VAR:
-		case semantics.FOR:
-		case semantics.IF:
-		case semantics.WHILE:
-		case semantics.PRINT:
-		case semantics.RETURN:
+		case semantics.CLASS, semantics.FUN, semantics.VAR, semantics.CONST, semantics.IMPORT, semantics.EXPORT,
+			semantics.FOR, semantics.IF, semantics.WHILE, semantics.PRINT, semantics.RETURN:
 			return
-
 		}
 		p.advance()
 	}
 }
 
-func (p *Parser) assignment() semantics.Expression {
-	expr := p.equality()
-
-	if p.match(semantics.EQUAL) {
-		equals := p.previous()
-		value := p.assignment()
-
-		if variable, ok := expr.(*semantics.Variable); ok {
-			// name := semantics.Variable(expr.(*semantics.Variable))
-			return &semantics.Assignment{Name: variable.Name, Value: value}
-		}
-		p.error(equals, "Invalid assignment type")
-	}
-
-	return expr
-}
-
 // NOTES
 // Understanding variable Declaration as a statement and
 // also the assignment and accessing of variable as an expression
 // program        → declaration* EOF ;
 
 // declaration    → varDecl
+//                | constDecl
 //                | statement ;
 
+// constDecl      → "const" IDENTIFIER "=" expression ";" ;
+
 // statement      → exprStmt
 //                | printStmt ;
 
+// printStmt      → "print" expression ( "," expression )* ";" ;
+
 // Expression for variable declaration
 // primary        → "true" | "false" | "nil"
 //                | NUMBER | STRING
@@ -405,5 +453,5 @@ func (p *Parser) assignment() semantics.Expression {
 
 // exprerssion tree turns into the below after variable assignment is added
 // expression     → assignment ;
-// assignment     → IDENTIFIER "=" assignment
+// assignment     → IDENTIFIER ( "=" | "+=" | "-=" | "*=" | "/=" ) assignment
 //                | equality ;
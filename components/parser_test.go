@@ -0,0 +1,35 @@
+package components
+
+import "testing"
+
+// after an error the parser skips to the next statement, so one mistake doesn't hide the next
+func TestParserRecoversAtTheNextStatement(t *testing.T) {
+	tests := []struct {
+		source string
+		lines  []int
+	}{
+		{"var a = 1 2 var b = ;", []int{1, 1}},
+		{"var a = 1 2 const b = ;", []int{1, 1}},
+		{"var a = 1 2\nimport 3 as m;", []int{1, 2}},
+		{"var a = 1 2\nexport var = 1;", []int{1, 2}},
+		{"print 1 2\nif (true print 3;", []int{1, 2}},
+		{"var a = (1;\nprint ;\nconst c = 3;", []int{1, 2}},
+	}
+	for _, test := range tests {
+		parser := InitParser(InitScanner(test.source).ScanTokens())
+		if _, err := parser.Parse(); err == nil {
+			t.Errorf("%q: want a parse error", test.source)
+			continue
+		}
+		errors := parser.Errors()
+		if len(errors) != len(test.lines) {
+			t.Errorf("%q: want %v errors, got %v", test.source, len(test.lines), errors)
+			continue
+		}
+		for i, err := range errors {
+			if line := err.(*ParseError).Token.Line; line != test.lines[i] {
+				t.Errorf("%q: error %v is on line %v, want %v", test.source, i+1, line, test.lines[i])
+			}
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package components
+
+import "scoop/semantics"
+
+/**
+ * Expressions are parsed with a Pratt parser (top down operator precedence).
+ *
+ * Every token that can start an expression has a prefix parselet and every token that
+ * can follow a complete expression has an infix parselet with a binding power.
+ * parsePrecedence(power) parses a prefix and then keeps folding infix operators into it
+ * for as long as they bind at least as tightly as power.
+ *
+ * A left associative operator parses its right operand one level tighter than itself,
+ * so a - b - c stops before the second "-" and becomes (a - b) - c. A right associative
+ * one parses it at its own level, so a = b = c becomes a = (b = c).
+ *
+ * Adding an operator is one line in the tables below, the levels follow C.
+ */
+
+// binding powers from the loosest to the tightest
+const (
+	bindNone       = iota
+	bindAssignment // = += -= *= /=            right
+	bindConditional
+	bindCoalesce   // ??
+	bindBitwiseOr  // |
+	bindBitwiseXor // ^
+	bindBitwiseAnd // &
+	bindEquality   // == !=
+	bindComparison // < <= > >=
+	bindShift      // << >>
+	bindTerm       // + -
+	bindFactor     // * / %
+	bindUnary      // ! - ~ ++x --x (prefix)
+	bindPower      // **                       right
+	bindPostfix    // x++ x--
+	bindCall       // . ?. ()
+)
+
+type prefixParselet func(p *Parser, token semantics.Token) semantics.Expression
+
+type infixParselet func(p *Parser, left semantics.Expression, token semantics.Token) semantics.Expression
+
+type infixRule struct {
+	power int
+	parse infixParselet
+}
+
+var prefixParselets map[semantics.TokenType]prefixParselet
+
+var infixRules map[semantics.TokenType]infixRule
+
+// the tables refer to parsePrecedence which reads them, so they are filled in init
+func init() {
+	prefixParselets = map[semantics.TokenType]prefixParselet{
+		semantics.NUMBER:      literal,
+		semantics.STRING:      literal,
+		semantics.TRUE:        literal,
+		semantics.FALSE:       literal,
+		semantics.NIL:         literal,
+		semantics.IDENTIFIER:  variable,
+		semantics.LEFT_PAREN:  grouping,
+		semantics.BANG:        unary,
+		semantics.MINUS:       unary,
+		semantics.TILDE:       unary,
+		semantics.PLUS_PLUS:   prefixUpdate,
+		semantics.MINUS_MINUS: prefixUpdate,
+	}
+
+	infixRules = map[semantics.TokenType]infixRule{
+		semantics.EQUAL:             {bindAssignment, assignment},
+		semantics.PLUS_EQUAL:        {bindAssignment, assignment},
+		semantics.MINUS_EQUAL:       {bindAssignment, assignment},
+		semantics.STAR_EQUAL:        {bindAssignment, assignment},
+		semantics.SLASH_EQUAL:       {bindAssignment, assignment},
+		semantics.QUESTION:          {bindConditional, conditional},
+		semantics.QUESTION_QUESTION: {bindCoalesce, logical},
+		semantics.PIPE:              {bindBitwiseOr, binary},
+		semantics.CARET:             {bindBitwiseXor, binary},
+		semantics.AMPERSAND:         {bindBitwiseAnd, binary},
+		semantics.EQUAL_EQUAL:       {bindEquality, binary},
+		semantics.BANG_EQUAL:        {bindEquality, binary},
+		semantics.GREATER:           {bindComparison, binary},
+		semantics.GREATER_EQUAL:     {bindComparison, binary},
+		semantics.LESS:              {bindComparison, binary},
+		semantics.LESS_EQUAL:        {bindComparison, binary},
+		semantics.LESS_LESS:         {bindShift, binary},
+		semantics.GREATER_GREATER:   {bindShift, binary},
+		semantics.PLUS:              {bindTerm, binary},
+		semantics.MINUS:             {bindTerm, binary},
+		semantics.STAR:              {bindFactor, binary},
+		semantics.SLASH:             {bindFactor, binary},
+		semantics.PERCENT:           {bindFactor, binary},
+		// the right operand of ** is parsed at the unary level, which takes in -x and more **
+		semantics.STAR_STAR:    {bindPower, power},
+		semantics.PLUS_PLUS:    {bindPostfix, postfixUpdate},
+		semantics.MINUS_MINUS:  {bindPostfix, postfixUpdate},
+		semantics.DOT:          {bindCall, get},
+		semantics.QUESTION_DOT: {bindCall, get},
+		semantics.LEFT_PAREN:   {bindCall, call},
+	}
+}
+
+func (p *Parser) expression() semantics.Expression {
+	return p.parsePrecedence(bindAssignment)
+}
+
+func (p *Parser) parsePrecedence(power int) semantics.Expression {
+	prefix, found := prefixParselets[p.peek().TokenType]
+	if !found {
+		panic(p.error(p.peek(), "Expect expression."))
+	}
+	left := prefix(p, p.advance())
+
+	for {
+		rule, found := infixRules[p.peek().TokenType]
+		if !found || rule.power < power {
+			return left
+		}
+		left = rule.parse(p, left, p.advance())
+	}
+}
+
+func literal(p *Parser, token semantics.Token) semantics.Expression {
+	switch token.TokenType {
+	case semantics.TRUE:
+		return semantics.InitLiteral(true)
+	case semantics.FALSE:
+		return semantics.InitLiteral(false)
+	case semantics.NIL:
+		return semantics.InitLiteral(nil)
+	}
+	return semantics.InitLiteral(token.Literal)
+}
+
+func variable(p *Parser, token semantics.Token) semantics.Expression {
+	return semantics.InitVariable(token)
+}
+
+func grouping(p *Parser, token semantics.Token) semantics.Expression {
+	expr := p.expression()
+	p.consume(semantics.RIGHT_PAREN, "Expect ')' after expression")
+	return semantics.InitGrouping(expr)
+}
+
+func unary(p *Parser, operator semantics.Token) semantics.Expression {
+	return semantics.InitUnary(operator, p.parsePrecedence(bindUnary))
+}
+
+func prefixUpdate(p *Parser, operator semantics.Token) semantics.Expression {
+	target := p.parsePrecedence(bindUnary)
+	return semantics.InitUpdate(p.updateTarget(operator, target), operator, true)
+}
+
+func postfixUpdate(p *Parser, target semantics.Expression, operator semantics.Token) semantics.Expression {
+	return semantics.InitUpdate(p.updateTarget(operator, target), operator, false)
+}
+
+// binary is a left associative operator
+func binary(p *Parser, left semantics.Expression, operator semantics.Token) semantics.Expression {
+	right := p.parsePrecedence(infixRules[operator.TokenType].power + 1)
+	return semantics.InitBinary(left, operator, right)
+}
+
+func power(p *Parser, left semantics.Expression, operator semantics.Token) semantics.Expression {
+	return semantics.InitBinary(left, operator, p.parsePrecedence(bindUnary))
+}
+
+func logical(p *Parser, left semantics.Expression, operator semantics.Token) semantics.Expression {
+	right := p.parsePrecedence(infixRules[operator.TokenType].power + 1)
+	return semantics.InitLogical(left, operator, right)
+}
+
+// conditional is right associative, a ? b : c ? d : e is a ? b : (c ? d : e)
+func conditional(p *Parser, condition semantics.Expression, question semantics.Token) semantics.Expression {
+	thenBranch := p.expression()
+	p.consume(semantics.COLON, "Expect ':' after the then branch of a conditional expression.")
+	elseBranch := p.parsePrecedence(bindConditional)
+	return semantics.InitConditional(condition, question, thenBranch, elseBranch)
+}
+
+// get is a module member, str.name or str?.name
+func get(p *Parser, object semantics.Expression, operator semantics.Token) semantics.Expression {
+	name := p.consume(semantics.IDENTIFIER, "Expect a name after '"+operator.Lexeme+"'.")
+	return semantics.InitGet(object, operator, name)
+}
+
+// call is a function call, callee(a, b)
+func call(p *Parser, callee semantics.Expression, paren semantics.Token) semantics.Expression {
+	arguments := []semantics.Expression{}
+	if !p.check(semantics.RIGHT_PAREN) {
+		arguments = append(arguments, p.expression())
+		for p.match(semantics.COMMA) {
+			arguments = append(arguments, p.expression())
+		}
+	}
+	closing := p.consume(semantics.RIGHT_PAREN, "Expect ')' after arguments.")
+	return semantics.InitCall(callee, closing, arguments)
+}
+
+func assignment(p *Parser, target semantics.Expression, equals semantics.Token) semantics.Expression {
+	value := p.parsePrecedence(bindAssignment)
+
+	if variable, ok := target.(*semantics.Variable); ok {
+		if equals.TokenType != semantics.EQUAL {
+			return semantics.InitCompoundAssignment(variable.Name, equals, value)
+		}
+		return &semantics.Assignment{Name: variable.Name, Value: value}
+	}
+	panic(p.error(equals, "Invalid assignment target."))
+}
@@ -0,0 +1,154 @@
+package components
+
+import (
+	"scoop/semantics"
+	"testing"
+)
+
+func parseExpression(t *testing.T, source string) (semantics.Expression, error) {
+	t.Helper()
+	return InitParser(InitScanner(source).ScanTokens()).ParseExpression()
+}
+
+// the expected trees are what the recursive descent parser before the Pratt parser printed
+func TestPrattParserMatchesRecursiveDescent(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"1", "1"},
+		{`"text"`, `"text"`},
+		{"true", "true"},
+		{"false", "false"},
+		{"nil", "nil"},
+		{"a", "a"},
+		{"(a)", "(group a)"},
+		{"!a", "(! a)"},
+		{"-a", "(- a)"},
+		{"~a", "(~ a)"},
+		{"++a", "(pre++ a)"},
+		{"--a", "(pre-- a)"},
+		{"a++", "(post++ a)"},
+		{"a--", "(post-- a)"},
+		{"a = 1", "(= a 1)"},
+		{"a += 1", "(+= a 1)"},
+		{"a -= 1", "(-= a 1)"},
+		{"a *= 2", "(*= a 2)"},
+		{"a /= 2", "(/= a 2)"},
+		{"a ? b : c", "(?: a b c)"},
+		{"a ?? b", "(?? a b)"},
+		{"a | b", "(| a b)"},
+		{"a ^ b", "(^ a b)"},
+		{"a & b", "(& a b)"},
+		{"a == b", "(== a b)"},
+		{"a != b", "(!= a b)"},
+		{"a < b", "(< a b)"},
+		{"a <= b", "(<= a b)"},
+		{"a > b", "(> a b)"},
+		{"a >= b", "(>= a b)"},
+		{"a << b", "(<< a b)"},
+		{"a >> b", "(>> a b)"},
+		{"a + b", "(+ a b)"},
+		{"a - b", "(- a b)"},
+		{"a * b", "(* a b)"},
+		{"a / b", "(/ a b)"},
+		{"a % b", "(% a b)"},
+		{"a ** b", "(** a b)"},
+
+		// associativity
+		{"a - b - c", "(- (- a b) c)"},
+		{"a / b * c", "(* (/ a b) c)"},
+		{"a == b == c", "(== (== a b) c)"},
+		{"a ?? b ?? c", "(?? (?? a b) c)"},
+		{"a ** b ** c", "(** a (** b c))"},
+		{"a = b = c", "(= a (= b c))"},
+		{"a += b = c", "(+= a (= b c))"},
+		{"a ? b : c ? d : e", "(?: a b (?: c d e))"},
+
+		// unary against **
+		{"-a ** b", "(- (** a b))"},
+		{"a ** -b", "(** a (- b))"},
+		{"!a == b", "(== (! a) b)"},
+		{"-a * b", "(* (- a) b)"},
+		{"-a++", "(- (post++ a))"},
+		{"a++ + b", "(+ (post++ a) b)"},
+
+		// precedence between levels
+		{"a == b < c", "(== a (< b c))"},
+		{"a < b == c", "(== (< a b) c)"},
+		{"a + b * c", "(+ a (* b c))"},
+		{"a << b + c", "(<< a (+ b c))"},
+		{"a & b | c ^ d", "(| (& a b) (^ c d))"},
+		{"a | b & c", "(| a (& b c))"},
+		{"a ?? b | c", "(?? a (| b c))"},
+		{"a == b ? c : d", "(?: (== a b) c d)"},
+		{"a = b ? c : d", "(= a (?: b c d))"},
+		{"a ? b = c : d", "(?: a (= b c) d)"},
+		{"a < b ? a : b ?? c", "(?: (< a b) a (?? b c))"},
+		{"1 + 2 * 3 - 4 / 5 % 6", "(- (+ 1 (* 2 3)) (% (/ 4 5) 6))"},
+
+		// grouping
+		{"(a + b) * c", "(* (group (+ a b)) c)"},
+		{"a * (b + c)", "(* a (group (+ b c)))"},
+		{"!(a < b)", "(! (group (< a b)))"},
+	}
+	for _, test := range tests {
+		expression, err := parseExpression(t, test.source)
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.source, err)
+			continue
+		}
+		if got := semantics.InitAbstractSyntaxTreePrinter().Print(expression); got != test.want {
+			t.Errorf("%v\n got %v\nwant %v", test.source, got, test.want)
+		}
+	}
+}
+
+func TestPrattParserCalls(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"f()", "(call f)"},
+		{"f(a, b + c)", "(call f a (+ b c))"},
+		{"math.sqrt(x)", "(call (. math sqrt) x)"},
+		{"m?.name", "(?. m name)"},
+		{"-f(x) ** 2", "(- (** (call f x) 2))"},
+		{`"a".upper().length()`, `(call (. (call (. "a" upper)) length))`},
+	}
+	for _, test := range tests {
+		expression, err := parseExpression(t, test.source)
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.source, err)
+			continue
+		}
+		if got := semantics.InitAbstractSyntaxTreePrinter().Print(expression); got != test.want {
+			t.Errorf("%v\n got %v\nwant %v", test.source, got, test.want)
+		}
+	}
+}
+
+func TestPrattParserErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"1 = 2", "Invalid assignment target."},
+		{"(a) = 1", "Invalid assignment target."},
+		{"a +", "Expect expression."},
+		{"(a", "Expect ')' after expression"},
+		{"++1", "Invalid ++ target, expect a variable."},
+		{"a b", "Expect end of expression."},
+	}
+	for _, test := range tests {
+		_, err := parseExpression(t, test.source)
+		parseErr, ok := err.(*ParseError)
+		if !ok {
+			t.Errorf("%v: want a ParseError, got %v", test.source, err)
+			continue
+		}
+		if parseErr.Message != test.want {
+			t.Errorf("%v: got %q, want %q", test.source, parseErr.Message, test.want)
+		}
+	}
+}
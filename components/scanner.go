@@ -1,20 +1,68 @@
 package components
 
 import (
+	"fmt"
+	"io"
 	"scoop/semantics"
+	"sort"
 	"strconv"
+	"strings"
 )
 
+// scanBufferSize is how much of the input is read at a time, the buffer only grows past it
+// for a single token longer than that such as a long string
+const scanBufferSize = 4096
+
+// Scanner turns source read from an io.Reader into tokens, on demand with Next or all at
+// once with ScanTokens. Only the input from the start of the token being scanned onwards
+// is kept, and none of a comment that makes no token, so scanning a large script needs a
+// bounded amount of memory.
+// Positions (start, current, lineStart) are offsets from the start of the whole input
 type Scanner struct {
-	source             string
-	tokens             []semantics.Token
+	reader io.Reader
+	buffer []byte
+	// offset of buffer[0] in the input
+	offset int
+	// set once the reader has nothing more to give
+	exhausted bool
+	// tokens scanned but not handed out by Next yet, scanning a character may produce none
+	pending            []semantics.Token
+	eof                *semantics.Token
 	start              int
 	current            int
 	line               int
 	reservedKeyWordMap map[string]semantics.TokenType
+	// when set comments are kept as COMMENT tokens instead of being thrown away
+	preserveTrivia bool
+	// set while scanning a comment that makes no token, its text is dropped as it is read
+	skipping bool
+	// offset of the first character of the current line, for columns
+	lineStart int
+	// position of the token being scanned, a string may span several lines
+	startLine   int
+	startColumn int
+	errors      []error
+}
+
+// ScanError is a character or string the scanner could not turn into a token,
+// scanning carries on after it so every error in the source is found
+type ScanError struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *ScanError) Error() string {
+	return fmt.Sprintf("Scan error at line %v column %v: %s", e.Line, e.Column, e.Message)
 }
 
+// InitScanner scans a source held in memory
 func InitScanner(source string) *Scanner {
+	return InitStreamScanner(strings.NewReader(source))
+}
+
+// InitStreamScanner scans source as it is read from reader
+func InitStreamScanner(reader io.Reader) *Scanner {
 	keywords := map[string]semantics.TokenType{
 		"and":    semantics.AND,
 		"class":  semantics.CLASS,
@@ -31,10 +79,15 @@ func InitScanner(source string) *Scanner {
 		"this":   semantics.THIS,
 		"true":   semantics.TRUE,
 		"var":    semantics.VAR,
+		"const":  semantics.CONST,
+		"import": semantics.IMPORT,
+		"as":     semantics.AS,
+		"export": semantics.EXPORT,
 		"while":  semantics.WHILE,
 	}
 	return &Scanner{
-		source:             source,
+		reader:             reader,
+		buffer:             make([]byte, 0, scanBufferSize),
 		start:              0,
 		current:            0,
 		line:               1,
@@ -42,27 +95,131 @@ func InitScanner(source string) *Scanner {
 	}
 }
 
+// Keywords returns the reserved words of the language in alphabetical order
+func Keywords() []string {
+	keywords := []string{}
+	for keyword := range InitScanner("").reservedKeyWordMap {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+	return keywords
+}
+
+// PreserveTrivia makes the scanner emit COMMENT tokens, tools such as the formatter need
+// them but the parser does not understand them so they must be filtered out before parsing.
+// Whitespace is not kept, it can be recovered from the Line of the surrounding tokens
+func (s *Scanner) PreserveTrivia() {
+	s.preserveTrivia = true
+}
+
+// ScanTokens scans the whole input and returns every token, the last one is EOF
 func (s *Scanner) ScanTokens() []semantics.Token {
-	for !s.isAtEnd() {
-		s.start = s.current
+	tokens := []semantics.Token{}
+	for {
+		token := s.Next()
+		tokens = append(tokens, token)
+		if token.TokenType == semantics.EOF {
+			return tokens
+		}
+	}
+}
+
+// Next scans just enough input to return the next token, once the input is used up
+// it keeps returning the EOF token
+func (s *Scanner) Next() semantics.Token {
+	for len(s.pending) == 0 {
+		if s.eof != nil {
+			return *s.eof
+		}
+		if s.isAtEnd() {
+			s.eof = &semantics.Token{
+				TokenType: semantics.EOF,
+				Lexeme:    "",
+				Literal:   nil,
+				Line:      s.line,
+				Column:    s.current - s.lineStart,
+			}
+			return *s.eof
+		}
+		s.beginToken()
 		s.scanToken()
 	}
 
-	s.tokens = append(s.tokens, semantics.Token{
-		TokenType: semantics.EOF,
-		Lexeme:    "",
-		Literal:   nil,
-		Line:      s.line,
-	})
-	return s.tokens
+	token := s.pending[0]
+	s.pending = s.pending[1:]
+	return token
+}
+
+// Errors returns the scan errors found so far
+func (s *Scanner) Errors() []error {
+	return s.errors
+}
+
+func (s *Scanner) error(message string) {
+	s.errors = append(s.errors, &ScanError{Line: s.startLine, Column: s.startColumn, Message: message})
+}
+
+// beginToken marks the current position as the start of the next lexeme
+func (s *Scanner) beginToken() {
+	s.start = s.current
+	s.skipping = false
+	s.startLine = s.line
+	s.startColumn = s.current - s.lineStart
+}
+
+func (s *Scanner) newline() {
+	s.line++
+	s.lineStart = s.current
+}
+
+// fill reads until the byte at position is in the buffer, it returns false when the input
+// ends before it. The lexeme being scanned is kept, everything before it is dropped
+func (s *Scanner) fill(position int) bool {
+	for position-s.offset >= len(s.buffer) {
+		if s.exhausted {
+			return false
+		}
+		if len(s.buffer) == cap(s.buffer) {
+			keep := s.start
+			if s.skipping {
+				keep = s.current
+			}
+			kept := s.buffer[keep-s.offset:]
+			if len(kept) > cap(s.buffer)/2 {
+				// the token is too long for the buffer
+				grown := make([]byte, len(kept), 2*cap(s.buffer))
+				copy(grown, kept)
+				s.buffer = grown
+			} else {
+				s.buffer = s.buffer[:copy(s.buffer, kept)]
+			}
+			s.offset = keep
+		}
+
+		read, err := s.reader.Read(s.buffer[len(s.buffer):cap(s.buffer)])
+		s.buffer = s.buffer[:len(s.buffer)+read]
+		if err != nil {
+			s.exhausted = true
+			if err != io.EOF {
+				s.error("Could not read the source: " + err.Error())
+			}
+		}
+	}
+	return true
+}
+
+// text returns the input between two positions, they must still be in the buffer
+func (s *Scanner) text(from int, to int) string {
+	return string(s.buffer[from-s.offset : to-s.offset])
 }
 
 func (s *Scanner) isAtEnd() bool {
-	return s.current >= len(s.source)
+	return !s.fill(s.current)
 }
 
 func (s *Scanner) advance() byte {
-	currentChar := s.source[s.current]
+	s.fill(s.current)
+	currentChar := s.buffer[s.current-s.offset]
 	s.current++
 	return currentChar
 }
@@ -72,22 +229,49 @@ func (s *Scanner) addEmptyToken(tokenType semantics.TokenType) {
 }
 
 func (s *Scanner) addToken(tokenType semantics.TokenType, literal interface{}) {
-	text := s.source[s.start:s.current]
-	s.tokens = append(s.tokens, semantics.Token{TokenType: tokenType, Lexeme: text, Literal: literal, Line: s.line})
+	text := s.text(s.start, s.current)
+	s.pending = append(s.pending, semantics.Token{TokenType: tokenType, Lexeme: text, Literal: literal, Line: s.startLine, Column: s.startColumn})
+}
+
+// blockComment skips a /* */ comment, comments nest so /* a /* b */ c */ is one comment
+func (s *Scanner) blockComment() {
+	s.skipping = !s.preserveTrivia
+	depth := 1
+	for depth > 0 {
+		if s.isAtEnd() {
+			s.error("Unterminated block comment.")
+			return
+		}
+		switch {
+		case s.peek() == '/' && s.peekNext() == '*':
+			s.advance()
+			s.advance()
+			depth++
+		case s.peek() == '*' && s.peekNext() == '/':
+			s.advance()
+			s.advance()
+			depth--
+		case s.advance() == '\n':
+			s.newline()
+		}
+	}
+	if s.preserveTrivia {
+		s.addEmptyToken(semantics.COMMENT)
+	}
 }
 
 func (s *Scanner) peek() byte {
 	if s.isAtEnd() {
 		return 0
 	}
-	return s.source[s.current]
+	return s.buffer[s.current-s.offset]
 }
 
 func (s *Scanner) peekNext() byte {
-	if s.current+1 >= len(s.source) {
+	if !s.fill(s.current + 1) {
 		return 0
 	}
-	return s.source[s.current+1]
+	return s.buffer[s.current+1-s.offset]
 }
 
 func (s *Scanner) scanToken() {
@@ -106,13 +290,51 @@ func (s *Scanner) scanToken() {
 	case '.':
 		s.addEmptyToken(semantics.DOT)
 	case '-':
-		s.addEmptyToken(semantics.MINUS)
+		if s.match('-') {
+			s.addEmptyToken(semantics.MINUS_MINUS)
+		} else if s.match('=') {
+			s.addEmptyToken(semantics.MINUS_EQUAL)
+		} else {
+			s.addEmptyToken(semantics.MINUS)
+		}
 	case '+':
-		s.addEmptyToken(semantics.PLUS)
+		if s.match('+') {
+			s.addEmptyToken(semantics.PLUS_PLUS)
+		} else if s.match('=') {
+			s.addEmptyToken(semantics.PLUS_EQUAL)
+		} else {
+			s.addEmptyToken(semantics.PLUS)
+		}
 	case ';':
 		s.addEmptyToken(semantics.SEMICOLON)
+	case '%':
+		s.addEmptyToken(semantics.PERCENT)
+	case '&':
+		s.addEmptyToken(semantics.AMPERSAND)
+	case '|':
+		s.addEmptyToken(semantics.PIPE)
+	case '^':
+		s.addEmptyToken(semantics.CARET)
+	case '~':
+		s.addEmptyToken(semantics.TILDE)
 	case '*':
-		s.addEmptyToken(semantics.STAR)
+		if s.match('*') {
+			s.addEmptyToken(semantics.STAR_STAR)
+		} else if s.match('=') {
+			s.addEmptyToken(semantics.STAR_EQUAL)
+		} else {
+			s.addEmptyToken(semantics.STAR)
+		}
+	case ':':
+		s.addEmptyToken(semantics.COLON)
+	case '?':
+		if s.match('?') {
+			s.addEmptyToken(semantics.QUESTION_QUESTION)
+		} else if s.match('.') {
+			s.addEmptyToken(semantics.QUESTION_DOT)
+		} else {
+			s.addEmptyToken(semantics.QUESTION)
+		}
 	case '!':
 		if s.match('=') {
 			s.addEmptyToken(semantics.BANG_EQUAL)
@@ -126,28 +348,44 @@ func (s *Scanner) scanToken() {
 			s.addEmptyToken(semantics.EQUAL)
 		}
 	case '<':
-		if s.match('=') {
+		if s.match('<') {
+			s.addEmptyToken(semantics.LESS_LESS)
+		} else if s.match('=') {
 			s.addEmptyToken(semantics.LESS_EQUAL)
 		} else {
 			s.addEmptyToken(semantics.LESS)
 		}
 	case '>':
-		if s.match('=') {
+		if s.match('>') {
+			s.addEmptyToken(semantics.GREATER_GREATER)
+		} else if s.match('=') {
 			s.addEmptyToken(semantics.GREATER_EQUAL)
 		} else {
 			s.addEmptyToken(semantics.GREATER)
 		}
 	case '/':
 		if s.match('/') {
+			// exactly three slashes, //// is an ordinary comment
+			docComment := s.peek() == '/' && s.peekNext() != '/'
+			s.skipping = !docComment && !s.preserveTrivia
 			for s.peek() != '\n' && !s.isAtEnd() {
 				s.advance()
 			}
+			if docComment {
+				s.addEmptyToken(semantics.DOC_COMMENT)
+			} else if s.preserveTrivia {
+				s.addEmptyToken(semantics.COMMENT)
+			}
+		} else if s.match('*') {
+			s.blockComment()
+		} else if s.match('=') {
+			s.addEmptyToken(semantics.SLASH_EQUAL)
 		} else {
 			s.addEmptyToken(semantics.SLASH)
 		}
 	case ' ', '\r', '\t':
 	case '\n':
-		s.line++
+		s.newline()
 	case '"':
 		s.string()
 	default:
@@ -156,8 +394,7 @@ func (s *Scanner) scanToken() {
 		} else if s.isAlpha(character) {
 			s.identifier()
 		} else {
-			// suppose to throw error here from scoop
-			// main.Report()
+			s.error(fmt.Sprintf("Unexpected character '%c'.", character))
 		}
 
 	}
@@ -166,19 +403,18 @@ func (s *Scanner) scanToken() {
 
 func (s *Scanner) string() {
 	for s.peek() != '"' && !s.isAtEnd() {
-		if s.peek() == '\n' {
-			s.line++
+		if s.advance() == '\n' {
+			s.newline()
 		}
-		s.advance()
 	}
 
 	if s.isAtEnd() {
-		// throw error here again ==> do this after refactor
+		s.error("Unterminated string.")
 		return
 	}
 
 	s.advance()
-	value := s.source[s.start+1 : s.current-1]
+	value := s.text(s.start+1, s.current-1)
 	s.addToken(semantics.STRING, value)
 }
 
@@ -195,9 +431,10 @@ func (s *Scanner) number() {
 			s.advance()
 		}
 	}
-	number, err := strconv.ParseFloat(s.source[s.start:s.current], 64)
+	text := s.text(s.start, s.current)
+	number, err := strconv.ParseFloat(text, 64)
 	if err != nil {
-		// do something here
+		s.error("Invalid number '" + text + "'.")
 	}
 	s.addToken(semantics.NUMBER, number)
 }
@@ -207,7 +444,7 @@ func (s *Scanner) identifier() {
 		s.advance()
 	}
 
-	text := s.source[s.start:s.current]
+	text := s.text(s.start, s.current)
 	tokenType, found := s.reservedKeyWordMap[text]
 
 	if !found {
@@ -239,7 +476,7 @@ func (s *Scanner) match(expected byte) bool {
 	if s.isAtEnd() {
 		return false
 	}
-	if s.source[s.current] != expected {
+	if s.buffer[s.current-s.offset] != expected {
 		return false
 	}
 	s.current++
@@ -0,0 +1,81 @@
+package components
+
+import (
+	"io"
+	"scoop/semantics"
+	"testing"
+)
+
+// longReader streams head, then filler repeated until size bytes, then tail, without
+// holding the whole input in memory
+type longReader struct {
+	head, filler, tail string
+	size, read         int
+}
+
+func (r *longReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		switch {
+		case r.read < len(r.head):
+			p[n] = r.head[r.read]
+		case r.read < len(r.head)+r.size:
+			p[n] = r.filler[(r.read-len(r.head))%len(r.filler)]
+		case r.read < len(r.head)+r.size+len(r.tail):
+			p[n] = r.tail[r.read-len(r.head)-r.size]
+		default:
+			return n, io.EOF
+		}
+		n++
+		r.read++
+	}
+	return n, nil
+}
+
+// a comment that makes no token is dropped as it is read, however long it is
+func TestScannerDropsLongComments(t *testing.T) {
+	const size = 1 << 22
+	tests := []struct {
+		name               string
+		head, filler, tail string
+		lines              int
+	}{
+		{"line comment", "// ", "a comment text. ", "", 0},
+		{"four slashes", "//// ", "a comment text. ", "", 0},
+		{"block comment", "/* /* nested */ ", "a comment text.\n", " */", size / 16},
+	}
+	for _, test := range tests {
+		scanner := InitStreamScanner(&longReader{head: test.head, filler: test.filler, tail: test.tail + "\nvar a;", size: size})
+		tokens := scanner.ScanTokens()
+
+		if len(scanner.Errors()) > 0 || len(tokens) != 4 || tokens[0].TokenType != semantics.VAR {
+			t.Errorf("%v: want var a; after the comment, got %v %v", test.name, tokens, scanner.Errors())
+			continue
+		}
+		if want := test.lines + 2; tokens[0].Line != want {
+			t.Errorf("%v: want var on line %v, got %v", test.name, want, tokens[0].Line)
+		}
+		if cap(scanner.buffer) > scanBufferSize {
+			t.Errorf("%v: want the buffer to stay at %v bytes, it grew to %v", test.name, scanBufferSize, cap(scanner.buffer))
+		}
+	}
+}
+
+// doc comments and, with PreserveTrivia, comments are tokens so their text is kept
+func TestScannerKeepsCommentTokens(t *testing.T) {
+	const size = 3 * scanBufferSize
+	scanner := InitStreamScanner(&longReader{head: "/// ", filler: "doc ", tail: "\nvar a;", size: size})
+	tokens := scanner.ScanTokens()
+	if tokens[0].TokenType != semantics.DOC_COMMENT || len(tokens[0].Lexeme) != size+4 {
+		t.Errorf("want the whole doc comment, got %v with %v bytes", tokens[0].TokenType, len(tokens[0].Lexeme))
+	}
+
+	for _, comment := range []struct{ head, tail string }{{"// ", ""}, {"/* ", " */"}} {
+		scanner := InitStreamScanner(&longReader{head: comment.head, filler: "text ", tail: comment.tail + "\nvar a;", size: size})
+		scanner.PreserveTrivia()
+		tokens := scanner.ScanTokens()
+		if want := len(comment.head) + size + len(comment.tail); tokens[0].TokenType != semantics.COMMENT || len(tokens[0].Lexeme) != want {
+			t.Errorf("%v: want the whole comment of %v bytes, got %v with %v bytes", comment.head, want, tokens[0].TokenType, len(tokens[0].Lexeme))
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package dap
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"scoop/components"
+	"scoop/debugger"
+	"scoop/semantics"
+	"sync"
+)
+
+// pause is where the script waits for the client
+type pause struct {
+	line   int
+	column int
+	depth  int
+	env    *semantics.Environment
+}
+
+// program is a launched script. Its interpreter runs on its own goroutine, everything
+// the two goroutines share is guarded by lock
+type program struct {
+	server      *Server
+	path        string
+	statements  []semantics.Statement
+	interpreter *semantics.Interpreter
+	// lines a statement starts on, only those can hold a breakpoint
+	statementLines map[int]bool
+	stopOnEntry    bool
+
+	lock    sync.Mutex
+	stepper *debugger.Stepper
+	started bool
+	paused  *pause
+	// variablesReference - 1 indexes the scopes and lists shown since the last pause
+	handles []interface{}
+	resumed chan error
+	quit    bool
+}
+
+// launch compiles the script, it only starts running on configurationDone
+func launch(server *Server, arguments *LaunchArguments) (*program, error) {
+	if arguments.Program == "" {
+		return nil, errors.New("launch needs the path of the program")
+	}
+	bytes, err := os.ReadFile(arguments.Program)
+	if err != nil {
+		return nil, err
+	}
+	loader := components.InitScriptLoader(components.SearchPath(os.Getenv("SCOOP_PATH")))
+	path, err := filepath.Abs(arguments.Program)
+	if err != nil {
+		return nil, err
+	}
+	statements, err := compile(string(bytes))
+	if err != nil {
+		return nil, err
+	}
+
+	p := &program{
+		server:         server,
+		path:           arguments.Program,
+		statements:     statements,
+		interpreter:    semantics.InitInterpreter(),
+		statementLines: map[int]bool{},
+		stopOnEntry:    arguments.StopOnEntry && !arguments.NoDebug,
+		stepper:        debugger.InitStepper(arguments.StopOnEntry && !arguments.NoDebug),
+		resumed:        make(chan error),
+	}
+	p.indexLines(statements)
+
+	elements := []interface{}{}
+	for _, arg := range arguments.Args {
+		elements = append(elements, arg)
+	}
+	p.interpreter.DefineGlobal("args", semantics.InitList(elements))
+	p.interpreter.SetOutput(&output{server: server})
+	p.interpreter.SetModuleLoader(loader)
+	p.interpreter.SetScriptPath(path)
+	if !arguments.NoDebug {
+		p.interpreter.SetStepHook(p.hook)
+	}
+	return p, nil
+}
+
+// compile scans and parses source, returning every error found as one
+func compile(source string) ([]semantics.Statement, error) {
+	scanner := components.InitScanner(source)
+	tokens := scanner.ScanTokens()
+	parser := components.InitParser(tokens)
+	statements, _ := parser.Parse()
+
+	problems := ""
+	for _, err := range scanner.Errors() {
+		if scanErr, ok := err.(*components.ScanError); ok {
+			problems += fmt.Sprintf("\n[line %v] Error : %v", scanErr.Line, scanErr.Message)
+		}
+	}
+	for _, err := range parser.Errors() {
+		if parseErr, ok := err.(*components.ParseError); ok {
+			problems += fmt.Sprintf("\n[line %v] Error at '%v' : %v", parseErr.Token.Line, parseErr.Token.Lexeme, parseErr.Message)
+		}
+	}
+	if problems != "" {
+		return nil, errors.New("the program has errors:" + problems)
+	}
+	return statements, nil
+}
+
+func (p *program) indexLines(statements []semantics.Statement) {
+	for _, statement := range statements {
+		p.statementLines[statement.Start().Line] = true
+		switch statement := statement.(type) {
+		case *semantics.Block:
+			p.indexLines(statement.Statements)
+		case *semantics.If:
+			p.indexLines([]semantics.Statement{statement.ThenBranch})
+			if statement.ElseBranch != nil {
+				p.indexLines([]semantics.Statement{statement.ElseBranch})
+			}
+		}
+	}
+}
+
+func (p *program) source() Source {
+	return Source{Name: filepath.Base(p.path), Path: p.path}
+}
+
+// setBreakpoints replaces all the breakpoints, lines without a statement are not verified
+func (p *program) setBreakpoints(lines []int) []Breakpoint {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.stepper.ClearBreakpoints()
+	breakpoints := []Breakpoint{}
+	for _, line := range lines {
+		breakpoint := Breakpoint{Verified: p.statementLines[line], Line: line, Source: p.source()}
+		if breakpoint.Verified {
+			p.stepper.SetBreakpoint(line)
+		} else {
+			breakpoint.Message = "No statement starts on this line."
+		}
+		breakpoints = append(breakpoints, breakpoint)
+	}
+	return breakpoints
+}
+
+// start runs the script and reports its end with the exited and terminated events
+func (p *program) start() {
+	p.lock.Lock()
+	if p.started {
+		p.lock.Unlock()
+		return
+	}
+	p.started = true
+	p.lock.Unlock()
+
+	go func() {
+		exitCode := 0
+		err := p.interpreter.Interprete(p.statements)
+		if err != nil && err != debugger.ErrQuit {
+			exitCode = 70
+			message := err.Error()
+			if runtimeErr, ok := err.(*semantics.RuntimeError); ok {
+				message = fmt.Sprintf("%v\n[line %v]", runtimeErr.Message, runtimeErr.Token.Line)
+			}
+			p.server.event("output", OutputEventBody{Category: "stderr", Output: message + "\n"})
+		}
+		p.server.event("exited", map[string]int{"exitCode": exitCode})
+		p.server.event("terminated", nil)
+	}()
+}
+
+// hook is the interpreter's StepHook, it blocks the script while it is paused
+func (p *program) hook(statement semantics.Statement, depth int, env *semantics.Environment) error {
+	p.lock.Lock()
+	if p.quit {
+		p.lock.Unlock()
+		return debugger.ErrQuit
+	}
+	start := statement.Start()
+	if !p.stepper.ShouldPause(start.Line, depth) {
+		p.lock.Unlock()
+		return nil
+	}
+
+	reason := "step"
+	if p.stopOnEntry {
+		reason = "entry"
+		p.stopOnEntry = false
+	} else if p.stepper.IsBreakpoint(start.Line) {
+		reason = "breakpoint"
+	}
+	p.paused = &pause{line: start.Line, column: start.Column, depth: depth, env: env}
+	p.handles = nil
+	p.lock.Unlock()
+
+	p.server.event("stopped", StoppedEventBody{Reason: reason, ThreadID: mainThread, AllThreadsStopped: true})
+	return <-p.resumed
+}
+
+func (p *program) pausedAt() *pause {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return p.paused
+}
+
+// resume sets up a paused script to carry on, stepping the way command ("next", "stepIn",
+// "continue") asks. It returns the function that lets the script go, the server calls it
+// after the response so the next "stopped" event can't arrive before it
+func (p *program) resume(command string) (func(), error) {
+	p.lock.Lock()
+	paused := p.paused
+	if paused == nil {
+		p.lock.Unlock()
+		return nil, errors.New("the program is not paused")
+	}
+	switch command {
+	case "next":
+		p.stepper.Next(paused.depth)
+	case "stepIn":
+		p.stepper.Step()
+	default:
+		p.stepper.Continue()
+	}
+	p.paused = nil
+	p.handles = nil
+	p.lock.Unlock()
+
+	return func() { p.resumed <- nil }, nil
+}
+
+func (p *program) stop() {
+	p.lock.Lock()
+	p.quit = true
+	paused := p.paused
+	p.paused = nil
+	p.lock.Unlock()
+
+	if paused != nil {
+		p.resumed <- debugger.ErrQuit
+	}
+}
+
+// handle returns the variablesReference of a scope or list, 0 for values without children
+func (p *program) handle(value interface{}) int {
+	switch value.(type) {
+	case *semantics.Environment, *semantics.List:
+		p.handles = append(p.handles, value)
+		return len(p.handles)
+	}
+	return 0
+}
+
+// scopes lists the environment chain of the paused statement, innermost first
+func (p *program) scopes() ([]Scope, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.paused == nil {
+		return nil, errors.New("the program is not paused")
+	}
+
+	scopes := []Scope{}
+	for level, env := range debugger.Scopes(p.paused.env) {
+		scope := Scope{Name: fmt.Sprintf("Block %v", level), PresentationHint: "locals", VariablesReference: p.handle(env)}
+		if env.Enclosing() == nil {
+			scope.Name = "Globals"
+			scope.PresentationHint = ""
+		}
+		scopes = append(scopes, scope)
+	}
+	return scopes, nil
+}
+
+func (p *program) variables(reference int) ([]Variable, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.paused == nil {
+		return nil, errors.New("the program is not paused")
+	}
+	if reference < 1 || reference > len(p.handles) {
+		return nil, fmt.Errorf("unknown variablesReference %v", reference)
+	}
+
+	variables := []Variable{}
+	switch container := p.handles[reference-1].(type) {
+	case *semantics.Environment:
+		for _, name := range container.Names() {
+			value, _ := container.Lookup(name)
+			variables = append(variables, p.variable(name, value))
+		}
+	case *semantics.List:
+		for index, element := range container.Elements {
+			variables = append(variables, p.variable(fmt.Sprintf("[%v]", index), element))
+		}
+	}
+	return variables, nil
+}
+
+func (p *program) variable(name string, value interface{}) Variable {
+	return Variable{Name: name, Value: p.interpreter.Stringify(value), Type: typeName(value), VariablesReference: p.handle(value)}
+}
+
+// evaluate computes an expression in the scope of the paused statement
+func (p *program) evaluate(source string) (string, int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.paused == nil {
+		return "", 0, errors.New("expressions can only be evaluated while the program is paused")
+	}
+
+	scanner := components.InitScanner(source)
+	tokens := scanner.ScanTokens()
+	if errs := scanner.Errors(); len(errs) > 0 {
+		return "", 0, errs[0]
+	}
+	expression, err := components.InitParser(tokens).ParseExpression()
+	if err != nil {
+		if parseErr, ok := err.(*components.ParseError); ok {
+			return "", 0, errors.New(parseErr.Message)
+		}
+		return "", 0, err
+	}
+
+	// the script's goroutine is blocked in hook, so the interpreter is free to use
+	value, err := p.interpreter.Evaluate(expression, p.paused.env)
+	if err != nil {
+		if runtimeErr, ok := err.(*semantics.RuntimeError); ok {
+			return "", 0, errors.New(runtimeErr.Message)
+		}
+		return "", 0, err
+	}
+	return p.interpreter.Stringify(value), p.handle(value), nil
+}
+
+func typeName(value interface{}) string {
+	return semantics.TypeName(value)
+}
+
+// output forwards what the script prints to the client as output events
+type output struct {
+	server *Server
+}
+
+func (o *output) Write(data []byte) (int, error) {
+	o.server.event("output", OutputEventBody{Category: "stdout", Output: string(data)})
+	return len(data), nil
+}
@@ -0,0 +1,131 @@
+package dap
+
+import "encoding/json"
+
+// The subset of the Debug Adapter Protocol the scoop adapter speaks. Every message
+// carries a seq number, responses point back at the request they answer
+
+type request struct {
+	Seq       int             `json:"seq"`
+	Type      string          `json:"type"`
+	Command   string          `json:"command"`
+	Arguments json.RawMessage `json:"arguments,omitempty"`
+}
+
+type response struct {
+	Seq        int         `json:"seq"`
+	Type       string      `json:"type"`
+	RequestSeq int         `json:"request_seq"`
+	Success    bool        `json:"success"`
+	Command    string      `json:"command"`
+	Message    string      `json:"message,omitempty"`
+	Body       interface{} `json:"body,omitempty"`
+}
+
+type event struct {
+	Seq   int         `json:"seq"`
+	Type  string      `json:"type"`
+	Event string      `json:"event"`
+	Body  interface{} `json:"body,omitempty"`
+}
+
+type InitializeArguments struct {
+	ClientID        string `json:"clientID"`
+	AdapterID       string `json:"adapterID"`
+	LinesStartAt1   *bool  `json:"linesStartAt1"`
+	ColumnsStartAt1 *bool  `json:"columnsStartAt1"`
+}
+
+type Capabilities struct {
+	SupportsConfigurationDoneRequest bool `json:"supportsConfigurationDoneRequest"`
+	SupportsEvaluateForHovers        bool `json:"supportsEvaluateForHovers"`
+	SupportsTerminateRequest         bool `json:"supportsTerminateRequest"`
+}
+
+type LaunchArguments struct {
+	Program     string   `json:"program"`
+	Args        []string `json:"args"`
+	StopOnEntry bool     `json:"stopOnEntry"`
+	NoDebug     bool     `json:"noDebug"`
+}
+
+type Source struct {
+	Name string `json:"name,omitempty"`
+	Path string `json:"path,omitempty"`
+}
+
+type SourceBreakpoint struct {
+	Line int `json:"line"`
+}
+
+type SetBreakpointsArguments struct {
+	Source      Source             `json:"source"`
+	Breakpoints []SourceBreakpoint `json:"breakpoints"`
+	// deprecated, older clients only send the lines
+	Lines []int `json:"lines"`
+}
+
+type Breakpoint struct {
+	Verified bool   `json:"verified"`
+	Line     int    `json:"line"`
+	Message  string `json:"message,omitempty"`
+	Source   Source `json:"source"`
+}
+
+type Thread struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type StackFrame struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Source Source `json:"source"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+type ScopesArguments struct {
+	FrameID int `json:"frameId"`
+}
+
+type Scope struct {
+	Name               string `json:"name"`
+	PresentationHint   string `json:"presentationHint,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+	Expensive          bool   `json:"expensive"`
+}
+
+type VariablesArguments struct {
+	VariablesReference int `json:"variablesReference"`
+}
+
+type Variable struct {
+	Name               string `json:"name"`
+	Value              string `json:"value"`
+	Type               string `json:"type,omitempty"`
+	VariablesReference int    `json:"variablesReference"`
+}
+
+type EvaluateArguments struct {
+	Expression string `json:"expression"`
+	FrameID    int    `json:"frameId"`
+	Context    string `json:"context"`
+}
+
+type StoppedEventBody struct {
+	Reason            string `json:"reason"`
+	ThreadID          int    `json:"threadId"`
+	AllThreadsStopped bool   `json:"allThreadsStopped"`
+}
+
+type OutputEventBody struct {
+	Category string `json:"category"`
+	Output   string `json:"output"`
+}
+
+// the adapter debugs a single threaded interpreter, this is its only thread and frame
+const (
+	mainThread = 1
+	mainFrame  = 1
+)
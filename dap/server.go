@@ -0,0 +1,261 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// Server is a Debug Adapter Protocol server for scoop. Requests are read on one
+// goroutine while the launched script runs on another, its StepHook blocks on a
+// channel whenever the Stepper decides to pause until next, stepIn or continue
+type Server struct {
+	reader    *bufio.Reader
+	writer    io.Writer
+	writeLock sync.Mutex
+	seq       int
+	// offsets between the client's lines and columns and scoop's (1 based lines, 0 based columns)
+	lineBase   int
+	columnBase int
+	program    *program
+}
+
+func InitServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		reader:     bufio.NewReader(in),
+		writer:     out,
+		lineBase:   0,
+		columnBase: 1,
+	}
+}
+
+// Serve handles requests until the client disconnects
+func (s *Server) Serve() error {
+	for {
+		request, err := s.read()
+		if err == io.EOF {
+			s.stop()
+			return errors.New("client closed the stream without disconnecting")
+		}
+		if err != nil {
+			return err
+		}
+
+		if !s.handle(request) {
+			return nil
+		}
+	}
+}
+
+// read returns the next request, each one is preceded by a Content-Length header
+func (s *Server) read() (*request, error) {
+	headers, err := textproto.NewReader(s.reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %v", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.reader, body); err != nil {
+		return nil, err
+	}
+
+	message := &request{}
+	if err := json.Unmarshal(body, message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+// write numbers the message and sends it, the script's goroutine writes events concurrently
+func (s *Server) write(setSeq func(seq int) interface{}) {
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	s.seq++
+	body, err := json.Marshal(setSeq(s.seq))
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *Server) respond(request *request, body interface{}) {
+	s.write(func(seq int) interface{} {
+		return &response{Seq: seq, Type: "response", RequestSeq: request.Seq, Success: true, Command: request.Command, Body: body}
+	})
+}
+
+func (s *Server) respondError(request *request, message string) {
+	s.write(func(seq int) interface{} {
+		return &response{Seq: seq, Type: "response", RequestSeq: request.Seq, Success: false, Command: request.Command, Message: message}
+	})
+}
+
+func (s *Server) event(name string, body interface{}) {
+	s.write(func(seq int) interface{} {
+		return &event{Seq: seq, Type: "event", Event: name, Body: body}
+	})
+}
+
+// handle answers one request and returns false once the session is over
+func (s *Server) handle(request *request) bool {
+	switch request.Command {
+	case "initialize":
+		arguments := &InitializeArguments{}
+		if !s.arguments(request, arguments) {
+			return true
+		}
+		if arguments.LinesStartAt1 != nil && !*arguments.LinesStartAt1 {
+			s.lineBase = -1
+		}
+		if arguments.ColumnsStartAt1 != nil && !*arguments.ColumnsStartAt1 {
+			s.columnBase = 0
+		}
+		s.respond(request, Capabilities{SupportsConfigurationDoneRequest: true, SupportsEvaluateForHovers: true, SupportsTerminateRequest: true})
+	case "launch":
+		arguments := &LaunchArguments{}
+		if !s.arguments(request, arguments) {
+			return true
+		}
+		program, err := launch(s, arguments)
+		if err != nil {
+			s.respondError(request, err.Error())
+			return true
+		}
+		s.program = program
+		s.respond(request, nil)
+		// the client answers with its breakpoints and then configurationDone
+		s.event("initialized", nil)
+	case "disconnect", "terminate":
+		s.stop()
+		s.respond(request, nil)
+		return request.Command == "terminate"
+	case "threads":
+		s.respond(request, map[string]interface{}{"threads": []Thread{{ID: mainThread, Name: "main"}}})
+	default:
+		if s.program == nil {
+			s.respondError(request, "'"+request.Command+"' needs a launched program")
+			return true
+		}
+		s.handleProgram(request)
+	}
+	return true
+}
+
+// handleProgram answers the requests about the launched script
+func (s *Server) handleProgram(request *request) {
+	program := s.program
+	switch request.Command {
+	case "setBreakpoints":
+		arguments := &SetBreakpointsArguments{}
+		if !s.arguments(request, arguments) {
+			return
+		}
+		lines := arguments.Lines
+		if arguments.Breakpoints != nil {
+			lines = []int{}
+			for _, breakpoint := range arguments.Breakpoints {
+				lines = append(lines, breakpoint.Line)
+			}
+		}
+		breakpoints := []Breakpoint{}
+		for _, line := range program.setBreakpoints(s.scoopLines(lines)) {
+			line.Line += s.lineBase
+			breakpoints = append(breakpoints, line)
+		}
+		s.respond(request, map[string]interface{}{"breakpoints": breakpoints})
+	case "configurationDone":
+		s.respond(request, nil)
+		program.start()
+	case "stackTrace":
+		frames := []StackFrame{}
+		if paused := program.pausedAt(); paused != nil {
+			frames = append(frames, StackFrame{
+				ID:     mainFrame,
+				Name:   "main",
+				Source: program.source(),
+				Line:   paused.line + s.lineBase,
+				Column: paused.column + s.columnBase,
+			})
+		}
+		s.respond(request, map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)})
+	case "scopes":
+		scopes, err := program.scopes()
+		if err != nil {
+			s.respondError(request, err.Error())
+			return
+		}
+		s.respond(request, map[string]interface{}{"scopes": scopes})
+	case "variables":
+		arguments := &VariablesArguments{}
+		if !s.arguments(request, arguments) {
+			return
+		}
+		variables, err := program.variables(arguments.VariablesReference)
+		if err != nil {
+			s.respondError(request, err.Error())
+			return
+		}
+		s.respond(request, map[string]interface{}{"variables": variables})
+	case "evaluate":
+		arguments := &EvaluateArguments{}
+		if !s.arguments(request, arguments) {
+			return
+		}
+		result, reference, err := program.evaluate(arguments.Expression)
+		if err != nil {
+			s.respondError(request, err.Error())
+			return
+		}
+		s.respond(request, map[string]interface{}{"result": result, "variablesReference": reference})
+	case "next", "stepIn", "continue":
+		release, err := program.resume(request.Command)
+		if err != nil {
+			s.respondError(request, err.Error())
+			return
+		}
+		if request.Command == "continue" {
+			s.respond(request, map[string]interface{}{"allThreadsContinued": true})
+		} else {
+			s.respond(request, nil)
+		}
+		release()
+	default:
+		s.respondError(request, "unsupported request '"+request.Command+"'")
+	}
+}
+
+func (s *Server) arguments(request *request, arguments interface{}) bool {
+	if len(request.Arguments) == 0 {
+		return true
+	}
+	if err := json.Unmarshal(request.Arguments, arguments); err != nil {
+		s.respondError(request, "invalid arguments: "+err.Error())
+		return false
+	}
+	return true
+}
+
+func (s *Server) scoopLines(lines []int) []int {
+	converted := []int{}
+	for _, line := range lines {
+		converted = append(converted, line-s.lineBase)
+	}
+	return converted
+}
+
+// stop ends a running script, a paused one is released with debugger.ErrQuit
+func (s *Server) stop() {
+	if s.program != nil {
+		s.program.stop()
+	}
+}
@@ -0,0 +1,354 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// message is any message the server sends, only the fields of its type are set
+type message struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Command    string          `json:"command"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Event      string          `json:"event"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client is a scripted DAP client, it sends requests over a pipe and waits for their
+// responses while keeping the events that arrive in between in order
+type client struct {
+	t        *testing.T
+	conn     net.Conn
+	seq      int
+	messages chan *message
+	events   []*message
+}
+
+func startSession(t *testing.T) *client {
+	serverConn, clientConn := net.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- InitServer(serverConn, serverConn).Serve()
+		serverConn.Close()
+	}()
+	t.Cleanup(func() {
+		clientConn.Close()
+		select {
+		case <-served:
+		case <-time.After(5 * time.Second):
+			t.Error("the server did not stop")
+		}
+	})
+
+	c := &client{t: t, conn: clientConn, messages: make(chan *message, 64)}
+	go c.readAll()
+	return c
+}
+
+func (c *client) readAll() {
+	reader := bufio.NewReader(c.conn)
+	defer close(c.messages)
+	for {
+		headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+		if err != nil {
+			return
+		}
+		length, err := strconv.Atoi(headers.Get("Content-Length"))
+		if err != nil {
+			return
+		}
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			return
+		}
+		received := &message{}
+		if err := json.Unmarshal(body, received); err != nil {
+			return
+		}
+		c.messages <- received
+	}
+}
+
+func (c *client) next() *message {
+	c.t.Helper()
+	select {
+	case received, ok := <-c.messages:
+		if !ok {
+			c.t.Fatal("the server closed the stream")
+		}
+		return received
+	case <-time.After(5 * time.Second):
+		c.t.Fatal("timed out waiting for the server")
+	}
+	return nil
+}
+
+// send writes a request without waiting for its response
+func (c *client) send(command string, arguments interface{}) {
+	c.t.Helper()
+	c.seq++
+	sent := map[string]interface{}{"seq": c.seq, "type": "request", "command": command}
+	if arguments != nil {
+		sent["arguments"] = arguments
+	}
+	body, _ := json.Marshal(sent)
+	if _, err := fmt.Fprintf(c.conn, "Content-Length: %d\r\n\r\n%s", len(body), body); err != nil {
+		c.t.Fatalf("%v: %v", command, err)
+	}
+}
+
+// request sends a command and returns the body of its successful response
+func (c *client) request(command string, arguments interface{}) json.RawMessage {
+	c.t.Helper()
+	c.send(command, arguments)
+
+	for {
+		received := c.next()
+		if received.Type == "event" {
+			c.events = append(c.events, received)
+			continue
+		}
+		if received.RequestSeq != c.seq || received.Command != command {
+			c.t.Fatalf("%v: unexpected response %+v", command, received)
+		}
+		if !received.Success {
+			c.t.Fatalf("%v failed: %v", command, received.Message)
+		}
+		return received.Body
+	}
+}
+
+// event waits for the named event, skipping the output events before it
+func (c *client) event(name string) json.RawMessage {
+	c.t.Helper()
+	for {
+		var received *message
+		if len(c.events) > 0 {
+			received, c.events = c.events[0], c.events[1:]
+		} else {
+			received = c.next()
+		}
+		if received.Type != "event" {
+			c.t.Fatalf("waiting for %v, got response %+v", name, received)
+		}
+		if received.Event == name {
+			return received.Body
+		}
+		if received.Event != "output" {
+			c.t.Fatalf("waiting for %v, got event %v", name, received.Event)
+		}
+	}
+}
+
+// stoppedAt waits for the script to pause and returns the reason and the line it is on
+func (c *client) stoppedAt() (string, int) {
+	c.t.Helper()
+	stopped := StoppedEventBody{}
+	decode(c.t, c.event("stopped"), &stopped)
+
+	trace := struct {
+		StackFrames []StackFrame `json:"stackFrames"`
+	}{}
+	decode(c.t, c.request("stackTrace", map[string]int{"threadId": mainThread}), &trace)
+	if len(trace.StackFrames) != 1 {
+		c.t.Fatalf("want one stack frame, got %+v", trace.StackFrames)
+	}
+	return stopped.Reason, trace.StackFrames[0].Line
+}
+
+func decode(t *testing.T, body json.RawMessage, value interface{}) {
+	t.Helper()
+	if err := json.Unmarshal(body, value); err != nil {
+		t.Fatalf("decoding %s: %v", body, err)
+	}
+}
+
+const debuggee = `var a = 1;
+var b = "two";
+{
+  var c = a + 1;
+  print c;
+}
+print a, b;
+`
+
+func TestDebugSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debuggee.scoop")
+	if err := os.WriteFile(path, []byte(debuggee), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := startSession(t)
+
+	capabilities := Capabilities{}
+	decode(t, c.request("initialize", map[string]interface{}{"adapterID": "scoop", "linesStartAt1": true, "columnsStartAt1": true}), &capabilities)
+	if !capabilities.SupportsConfigurationDoneRequest {
+		t.Error("the adapter should support configurationDone")
+	}
+	c.request("launch", LaunchArguments{Program: path})
+	c.event("initialized")
+
+	breakpoints := struct {
+		Breakpoints []Breakpoint `json:"breakpoints"`
+	}{}
+	decode(t, c.request("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: path}, Breakpoints: []SourceBreakpoint{{Line: 2}, {Line: 6}}}), &breakpoints)
+	if len(breakpoints.Breakpoints) != 2 || !breakpoints.Breakpoints[0].Verified || breakpoints.Breakpoints[1].Verified {
+		t.Fatalf("want line 2 verified and line 6 (a closing brace) not, got %+v", breakpoints.Breakpoints)
+	}
+	c.request("configurationDone", nil)
+
+	if reason, line := c.stoppedAt(); reason != "breakpoint" || line != 2 {
+		t.Fatalf("want a breakpoint stop on line 2, got %v on line %v", reason, line)
+	}
+
+	scopes := struct {
+		Scopes []Scope `json:"scopes"`
+	}{}
+	decode(t, c.request("scopes", ScopesArguments{FrameID: mainFrame}), &scopes)
+	if len(scopes.Scopes) != 1 || scopes.Scopes[0].Name != "Globals" {
+		t.Fatalf("want only the globals at the top level, got %+v", scopes.Scopes)
+	}
+	variables := struct {
+		Variables []Variable `json:"variables"`
+	}{}
+	decode(t, c.request("variables", VariablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference}), &variables)
+	found := false
+	for _, variable := range variables.Variables {
+		if variable.Name == "a" {
+			found = true
+			if variable.Value != "1" || variable.Type != "number" {
+				t.Errorf("want a = 1 of type number, got %+v", variable)
+			}
+		}
+		if variable.Name == "b" {
+			t.Errorf("b is declared on the line the script is paused on, it should not be defined yet")
+		}
+	}
+	if !found {
+		t.Errorf("a is missing from the globals %+v", variables.Variables)
+	}
+
+	evaluated := struct {
+		Result string `json:"result"`
+	}{}
+	decode(t, c.request("evaluate", EvaluateArguments{Expression: "a + 41", FrameID: mainFrame, Context: "repl"}), &evaluated)
+	if evaluated.Result != "42" {
+		t.Errorf("want a + 41 to be 42, got %q", evaluated.Result)
+	}
+
+	c.request("next", map[string]int{"threadId": mainThread})
+	if reason, line := c.stoppedAt(); reason != "step" || line != 3 {
+		t.Fatalf("want next to stop on the block on line 3, got %v on line %v", reason, line)
+	}
+	c.request("stepIn", map[string]int{"threadId": mainThread})
+	if reason, line := c.stoppedAt(); reason != "step" || line != 4 {
+		t.Fatalf("want stepIn to enter the block on line 4, got %v on line %v", reason, line)
+	}
+	c.request("next", map[string]int{"threadId": mainThread})
+	if _, line := c.stoppedAt(); line != 5 {
+		t.Fatalf("want next to stop on line 5, got line %v", line)
+	}
+
+	decode(t, c.request("scopes", ScopesArguments{FrameID: mainFrame}), &scopes)
+	if len(scopes.Scopes) != 2 || scopes.Scopes[1].Name != "Globals" {
+		t.Fatalf("want the block and the globals, got %+v", scopes.Scopes)
+	}
+	decode(t, c.request("variables", VariablesArguments{VariablesReference: scopes.Scopes[0].VariablesReference}), &variables)
+	if len(variables.Variables) != 1 || variables.Variables[0].Name != "c" || variables.Variables[0].Value != "2" {
+		t.Errorf("want c = 2 in the block, got %+v", variables.Variables)
+	}
+
+	c.request("continue", map[string]int{"threadId": mainThread})
+	output := ""
+	for {
+		var received *message
+		if len(c.events) > 0 {
+			received, c.events = c.events[0], c.events[1:]
+		} else {
+			received = c.next()
+		}
+		if received.Event == "output" {
+			body := OutputEventBody{}
+			decode(t, received.Body, &body)
+			output += body.Output
+			continue
+		}
+		if received.Event != "exited" {
+			t.Fatalf("want the script to exit, got %+v", received)
+		}
+		exited := map[string]int{}
+		decode(t, received.Body, &exited)
+		if exited["exitCode"] != 0 {
+			t.Errorf("want exit code 0, got %v", exited["exitCode"])
+		}
+		break
+	}
+	c.event("terminated")
+	if want := ">> 2\n>> 1 two\n"; output != want {
+		t.Errorf("want the output %q, got %q", want, output)
+	}
+	c.request("disconnect", nil)
+}
+
+func TestRequestsNeedALaunchedProgram(t *testing.T) {
+	c := startSession(t)
+	c.request("initialize", map[string]interface{}{"adapterID": "scoop"})
+
+	c.seq++
+	fmt.Fprintf(c.conn, "Content-Length: %d\r\n\r\n%s", len(`{"seq":2,"type":"request","command":"stackTrace"}`), `{"seq":2,"type":"request","command":"stackTrace"}`)
+	received := c.next()
+	if received.Success || !strings.Contains(received.Message, "needs a launched program") {
+		t.Errorf("want stackTrace to fail before launch, got %+v", received)
+	}
+	c.request("disconnect", nil)
+}
+
+// a client may only see the next stop of the script after the response to the request
+// that resumed it
+func TestResumeIsAnsweredBeforeTheNextStop(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "steps.scoop")
+	source := strings.Repeat("var a = 1;\n", 30)
+	if err := os.WriteFile(path, []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c := startSession(t)
+	c.request("initialize", map[string]interface{}{"adapterID": "scoop"})
+	c.request("launch", LaunchArguments{Program: path, StopOnEntry: true})
+	c.event("initialized")
+	c.request("configurationDone", nil)
+	c.event("stopped")
+
+	for line := 2; line <= 30; line++ {
+		command := "next"
+		if line%2 == 0 {
+			command = "stepIn"
+		}
+		c.send(command, map[string]int{"threadId": mainThread})
+		if received := c.next(); received.Type != "response" || received.Command != command || !received.Success {
+			t.Fatalf("line %v: want the %v response first, got %+v", line, command, received)
+		}
+		if received := c.next(); received.Event != "stopped" {
+			t.Fatalf("line %v: want the stopped event after the response, got %+v", line, received)
+		}
+	}
+
+	c.send("continue", map[string]int{"threadId": mainThread})
+	if received := c.next(); received.Type != "response" || received.Command != "continue" {
+		t.Fatalf("want the continue response before the script ends, got %+v", received)
+	}
+	c.event("exited")
+	c.event("terminated")
+	c.request("disconnect", nil)
+}
@@ -0,0 +1,206 @@
+package debugger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"scoop/semantics"
+	"strconv"
+	"strings"
+)
+
+// ErrQuit is returned by the interpreter when the user quits the debugger
+var ErrQuit = errors.New("debugger quit")
+
+// Debugger is a terminal step debugger, install Hook as the interpreter's StepHook.
+// It pauses before the first statement and then whenever the user asked it to, reading
+// commands from in and writing to out
+type Debugger struct {
+	interpreter *semantics.Interpreter
+	lines       []string
+	in          *bufio.Scanner
+	out         io.Writer
+	stepper     *Stepper
+}
+
+func InitDebugger(interpreter *semantics.Interpreter, source string, in io.Reader, out io.Writer) *Debugger {
+	return &Debugger{
+		interpreter: interpreter,
+		lines:       strings.Split(source, "\n"),
+		in:          bufio.NewScanner(in),
+		out:         out,
+		stepper:     InitStepper(true),
+	}
+}
+
+func (d *Debugger) SetBreakpoint(line int) {
+	d.stepper.SetBreakpoint(line)
+}
+
+// Hook decides whether to pause before the statement and, when it does, runs the prompt
+func (d *Debugger) Hook(statement semantics.Statement, depth int, env *semantics.Environment) error {
+	line := statement.Start().Line
+	if !d.stepper.ShouldPause(line, depth) {
+		return nil
+	}
+
+	d.printLocation(line)
+	return d.prompt(depth, env)
+}
+
+func (d *Debugger) prompt(depth int, env *semantics.Environment) error {
+	for {
+		fmt.Fprint(d.out, "(scoop-debug) ")
+		if !d.in.Scan() {
+			// no more commands, let the program finish
+			d.stepper.ClearBreakpoints()
+			d.stepper.Continue()
+			return nil
+		}
+
+		fields := strings.Fields(d.in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		command, args := fields[0], fields[1:]
+
+		switch command {
+		case "s", "step":
+			d.stepper.Step()
+			return nil
+		case "n", "next":
+			d.stepper.Next(depth)
+			return nil
+		case "c", "continue":
+			d.stepper.Continue()
+			return nil
+		case "b", "break":
+			d.breakCommand(args, true)
+		case "d", "delete":
+			d.breakCommand(args, false)
+		case "v", "vars":
+			d.printScopes(env)
+		case "p", "print":
+			d.printVariables(env, args)
+		case "l", "list":
+			d.printSource(d.stepper.Line(), 3)
+		case "q", "quit":
+			return ErrQuit
+		case "h", "help":
+			d.printHelp()
+		default:
+			fmt.Fprintf(d.out, "unknown command '%v', type help for the list of commands\n", command)
+		}
+	}
+}
+
+func (d *Debugger) breakCommand(args []string, set bool) {
+	if len(args) == 0 {
+		fmt.Fprintf(d.out, "breakpoints: %v\n", d.stepper.Breakpoints())
+		return
+	}
+
+	for _, arg := range args {
+		line, err := strconv.Atoi(arg)
+		if err != nil || line < 1 || line > len(d.lines) {
+			fmt.Fprintf(d.out, "'%v' is not a line of the script\n", arg)
+			continue
+		}
+		if set {
+			d.stepper.SetBreakpoint(line)
+			fmt.Fprintf(d.out, "breakpoint set on line %v\n", line)
+		} else {
+			d.stepper.ClearBreakpoint(line)
+			fmt.Fprintf(d.out, "breakpoint removed from line %v\n", line)
+		}
+	}
+}
+
+// printScopes prints every level of the environment chain, innermost first
+func (d *Debugger) printScopes(env *semantics.Environment) {
+	seen := map[string]bool{}
+	for level, scope := range Scopes(env) {
+		name := fmt.Sprintf("block scope %v", level)
+		if scope.Enclosing() == nil {
+			name = "globals"
+		}
+		fmt.Fprintf(d.out, "%v:\n", name)
+
+		for _, variable := range scope.Names() {
+			value, _ := scope.Lookup(variable)
+			line := fmt.Sprintf("  %v = %v", variable, d.interpreter.Stringify(value))
+			if seen[variable] {
+				line += " (shadowed)"
+			}
+			seen[variable] = true
+			fmt.Fprintln(d.out, line)
+		}
+	}
+}
+
+func (d *Debugger) printVariables(env *semantics.Environment, names []string) {
+	if len(names) == 0 {
+		d.printScopes(env)
+		return
+	}
+	for _, name := range names {
+		value, found := Lookup(env, name)
+		if !found {
+			fmt.Fprintf(d.out, "%v is not defined here\n", name)
+			continue
+		}
+		fmt.Fprintf(d.out, "%v = %v\n", name, d.interpreter.Stringify(value))
+	}
+}
+
+func (d *Debugger) printLocation(line int) {
+	if line >= 1 && line <= len(d.lines) {
+		fmt.Fprintf(d.out, "-> %4d | %v\n", line, d.lines[line-1])
+	}
+}
+
+func (d *Debugger) printSource(line int, around int) {
+	for current := max(line-around, 1); current <= min(line+around, len(d.lines)); current++ {
+		marker := "  "
+		if current == line {
+			marker = "->"
+		}
+		if d.stepper.HasBreakpoint(current) {
+			marker = strings.Replace(marker, " ", "*", 1)
+		}
+		fmt.Fprintf(d.out, "%v %4d | %v\n", marker, current, d.lines[current-1])
+	}
+}
+
+func (d *Debugger) printHelp() {
+	fmt.Fprintln(d.out, `commands:
+  s, step          run the next statement, stopping inside blocks
+  n, next          run the next statement, stepping over blocks
+  c, continue      run until the next breakpoint
+  b, break LINE    set a breakpoint (no LINE lists them)
+  d, delete LINE   remove a breakpoint
+  v, vars          print the variables of every scope, innermost first
+  p, print NAME    print a variable
+  l, list          show the source around the current line
+  q, quit          stop the program`)
+}
+
+// Scopes returns the environment chain starting at env, the globals come last
+func Scopes(env *semantics.Environment) []*semantics.Environment {
+	scopes := []*semantics.Environment{}
+	for scope := env; scope != nil; scope = scope.Enclosing() {
+		scopes = append(scopes, scope)
+	}
+	return scopes
+}
+
+// Lookup finds a variable the way the interpreter would from env
+func Lookup(env *semantics.Environment, name string) (interface{}, bool) {
+	for _, scope := range Scopes(env) {
+		if value, found := scope.Lookup(name); found {
+			return value, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,134 @@
+package debugger
+
+import (
+	"scoop/components"
+	"scoop/semantics"
+	"strings"
+	"testing"
+)
+
+const script = `var a = 1;
+{
+  var b = a + 1;
+  print b;
+}
+var c = 3;
+print a, c;
+`
+
+// debug runs script under a Debugger fed with commands, returning what the debugger
+// and what the script printed
+func debug(t *testing.T, commands string, breakpoints ...int) (string, string, error) {
+	t.Helper()
+	statements, err := components.InitParser(components.InitScanner(script).ScanTokens()).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var session, output strings.Builder
+	interpreter := semantics.InitInterpreter()
+	interpreter.SetOutput(&output)
+	interpreter.SetRawPrint(true)
+	debugger := InitDebugger(interpreter, script, strings.NewReader(commands), &session)
+	for _, line := range breakpoints {
+		debugger.SetBreakpoint(line)
+	}
+	interpreter.SetStepHook(debugger.Hook)
+	err = interpreter.Interprete(statements)
+	return session.String(), output.String(), err
+}
+
+// stops lists the lines the debugger paused on, in order
+func stops(session string) []string {
+	lines := []string{}
+	for _, line := range strings.Split(session, "\n") {
+		if index := strings.Index(line, "-> "); index >= 0 && strings.Contains(line, " | ") {
+			lines = append(lines, strings.TrimSpace(strings.Split(line[index+3:], "|")[0]))
+		}
+	}
+	return lines
+}
+
+func TestDebuggerStepsThroughAScript(t *testing.T) {
+	session, output, err := debug(t, "p a\nn\ns\nn\nvars\nb 7\nc\np c b\nc\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(stops(session), ","); got != "1,2,3,4,7" {
+		t.Errorf("want the debugger to pause on lines 1,2,3,4,7, got %v\n%v", got, session)
+	}
+	for _, want := range []string{
+		"a is not defined here",
+		"block scope 0:\n  b = 2\nglobals:\n",
+		"  a = 1\n",
+		"breakpoint set on line 7",
+		"c = 3\nb is not defined here",
+	} {
+		if !strings.Contains(session, want) {
+			t.Errorf("want the session to contain %q, got\n%v", want, session)
+		}
+	}
+	if output != "2\n1 3\n" {
+		t.Errorf("want the script to print normally, got %q", output)
+	}
+}
+
+func TestDebuggerCommands(t *testing.T) {
+	session, output, err := debug(t, "c\n", 4)
+	if err != nil || strings.Join(stops(session), ",") != "1,4" {
+		t.Errorf("want continue to run to the breakpoint on line 4, got %v %v\n%v", stops(session), err, session)
+	}
+	if output != "2\n1 3\n" {
+		t.Errorf("want the script to finish once the commands run out, got %q", output)
+	}
+
+	session, _, _ = debug(t, "b 0 x 99\nd 4\nb\nl\nfoo\nc\n", 4)
+	for _, want := range []string{
+		"'0' is not a line of the script",
+		"'x' is not a line of the script",
+		"'99' is not a line of the script",
+		"breakpoint removed from line 4",
+		"breakpoints: []",
+		"->    1 | var a = 1;",
+		"unknown command 'foo'",
+	} {
+		if !strings.Contains(session, want) {
+			t.Errorf("want the session to contain %q, got\n%v", want, session)
+		}
+	}
+
+	_, output, err = debug(t, "n\nq\n")
+	if err != ErrQuit || output != "" {
+		t.Errorf("want quit to stop the script before it prints, got %q %v", output, err)
+	}
+}
+
+// stepping into an if pauses in the branch its condition picks, the interpreter used to
+// take the then branch without evaluating the condition
+func TestDebuggerStepsIntoTheBranchTaken(t *testing.T) {
+	source := `var n = 0;
+if (n++ > 0)
+  print "then";
+else
+  print "else";
+print n;
+`
+	statements, err := components.InitParser(components.InitScanner(source).ScanTokens()).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var session, output strings.Builder
+	interpreter := semantics.InitInterpreter()
+	interpreter.SetOutput(&output)
+	interpreter.SetRawPrint(true)
+	debugger := InitDebugger(interpreter, source, strings.NewReader("s\ns\ns\nc\n"), &session)
+	interpreter.SetStepHook(debugger.Hook)
+	if err := interpreter.Interprete(statements); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(stops(session.String()), ","); got != "1,2,5,6" {
+		t.Errorf("want to step from the if into the else branch on line 5, got %v\n%v", got, session.String())
+	}
+	if output.String() != "else\n1\n" {
+		t.Errorf("want the condition evaluated once, got %q", output.String())
+	}
+}
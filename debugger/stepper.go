@@ -0,0 +1,101 @@
+package debugger
+
+import "sort"
+
+type mode int
+
+const (
+	stepping mode = iota
+	steppingOver
+	running
+)
+
+// Stepper decides before which statements a debugged program pauses,
+// it is shared by the terminal debugger and the Debug Adapter Protocol server
+type Stepper struct {
+	breakpoints map[int]bool
+	mode        mode
+	// steppingOver pauses again at the first statement this deep or shallower
+	stopDepth    int
+	previousLine int
+}
+
+// InitStepper returns a Stepper that pauses before the first statement when stopOnEntry is set
+func InitStepper(stopOnEntry bool) *Stepper {
+	stepper := &Stepper{breakpoints: map[int]bool{}, mode: running}
+	if stopOnEntry {
+		stepper.mode = stepping
+	}
+	return stepper
+}
+
+// ShouldPause is called before every statement with its line and depth
+func (s *Stepper) ShouldPause(line int, depth int) bool {
+	previousLine := s.previousLine
+	s.previousLine = line
+
+	pause := false
+	switch s.mode {
+	case stepping:
+		pause = true
+	case steppingOver:
+		pause = depth <= s.stopDepth
+	}
+	// a breakpoint stops once per visit of its line, not before every statement on it
+	if s.breakpoints[line] && line != previousLine {
+		pause = true
+	}
+	return pause
+}
+
+// IsBreakpoint reports whether the pause at line is caused by a breakpoint rather than a step
+func (s *Stepper) IsBreakpoint(line int) bool {
+	return s.breakpoints[line] && s.mode == running
+}
+
+// Line is the line of the statement the program is paused before
+func (s *Stepper) Line() int {
+	return s.previousLine
+}
+
+// Step pauses at the very next statement, entering blocks
+func (s *Stepper) Step() {
+	s.mode = stepping
+}
+
+// Next pauses at the next statement that is not nested deeper than depth
+func (s *Stepper) Next(depth int) {
+	s.mode = steppingOver
+	s.stopDepth = depth
+}
+
+// Continue runs until a breakpoint is reached
+func (s *Stepper) Continue() {
+	s.mode = running
+}
+
+func (s *Stepper) SetBreakpoint(line int) {
+	s.breakpoints[line] = true
+}
+
+func (s *Stepper) ClearBreakpoint(line int) {
+	delete(s.breakpoints, line)
+}
+
+func (s *Stepper) ClearBreakpoints() {
+	s.breakpoints = map[int]bool{}
+}
+
+func (s *Stepper) HasBreakpoint(line int) bool {
+	return s.breakpoints[line]
+}
+
+// Breakpoints returns the lines with a breakpoint in order
+func (s *Stepper) Breakpoints() []int {
+	lines := []int{}
+	for line := range s.breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
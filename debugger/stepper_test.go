@@ -0,0 +1,81 @@
+package debugger
+
+import (
+	"reflect"
+	"testing"
+)
+
+// visit is a statement the program is about to run, at a line and block depth
+type visit struct {
+	line  int
+	depth int
+}
+
+// pauses returns the lines of the visits the stepper pauses at
+func pauses(stepper *Stepper, visits []visit) []int {
+	lines := []int{}
+	for _, v := range visits {
+		if stepper.ShouldPause(v.line, v.depth) {
+			lines = append(lines, v.line)
+		}
+	}
+	return lines
+}
+
+func TestStepperModes(t *testing.T) {
+	// var a; { var b; var c; } var d;
+	visits := []visit{{1, 0}, {2, 0}, {3, 1}, {4, 1}, {6, 0}}
+
+	if got := pauses(InitStepper(false), visits); len(got) != 0 {
+		t.Errorf("want no pause without a breakpoint or stopOnEntry, got %v", got)
+	}
+	if got := pauses(InitStepper(true), visits); !reflect.DeepEqual(got, []int{1, 2, 3, 4, 6}) {
+		t.Errorf("want stepping to pause before every statement, got %v", got)
+	}
+
+	stepper := InitStepper(false)
+	stepper.Next(0)
+	if got := pauses(stepper, visits); !reflect.DeepEqual(got, []int{1, 2, 6}) {
+		t.Errorf("want next at depth 0 to skip the statements of the block, got %v", got)
+	}
+	stepper.Next(1)
+	if got := pauses(stepper, visits); !reflect.DeepEqual(got, []int{1, 2, 3, 4, 6}) {
+		t.Errorf("want next at depth 1 to pause at depth 1 and leaving the block, got %v", got)
+	}
+
+	stepper = InitStepper(true)
+	stepper.Continue()
+	stepper.SetBreakpoint(4)
+	if got := pauses(stepper, visits); !reflect.DeepEqual(got, []int{4}) {
+		t.Errorf("want continue to pause only at the breakpoint, got %v", got)
+	}
+	if !stepper.IsBreakpoint(4) || stepper.Line() != 6 {
+		t.Errorf("want line 4 to be a breakpoint while running and the last line seen to be 6, got %v", stepper.Line())
+	}
+	stepper.Step()
+	if stepper.IsBreakpoint(4) {
+		t.Error("a pause while stepping is a step, not a breakpoint")
+	}
+}
+
+func TestStepperBreakpoints(t *testing.T) {
+	stepper := InitStepper(false)
+	stepper.SetBreakpoint(7)
+	stepper.SetBreakpoint(2)
+	stepper.SetBreakpoint(5)
+	stepper.ClearBreakpoint(5)
+	if got := stepper.Breakpoints(); !reflect.DeepEqual(got, []int{2, 7}) {
+		t.Errorf("want the breakpoints in order, got %v", got)
+	}
+
+	// a loop body on one line runs several statements per visit, and visits it again
+	visits := []visit{{2, 1}, {2, 1}, {3, 1}, {2, 1}, {2, 1}}
+	if got := pauses(stepper, visits); !reflect.DeepEqual(got, []int{2, 2}) {
+		t.Errorf("want a breakpoint to pause once per visit of its line, got %v", got)
+	}
+
+	stepper.ClearBreakpoints()
+	if stepper.HasBreakpoint(2) || len(stepper.Breakpoints()) != 0 {
+		t.Errorf("want no breakpoints left, got %v", stepper.Breakpoints())
+	}
+}
@@ -0,0 +1,233 @@
+package lsp
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"scoop/components"
+	"scoop/semantics"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is an open file analysed the way "scoop check" and "scoop lint" would,
+// it is rebuilt from scratch on every change
+type document struct {
+	uri         string
+	text        string
+	lines       []string
+	references  []*semantics.Reference
+	diagnostics []Diagnostic
+}
+
+func analyse(uri string, text string) *document {
+	d := &document{uri: uri, text: text, lines: strings.Split(text, "\n"), diagnostics: []Diagnostic{}}
+
+	scanner := components.InitScanner(text)
+	tokens := scanner.ScanTokens()
+	for _, err := range scanner.Errors() {
+		if scanErr, ok := err.(*components.ScanError); ok {
+			start := d.position(scanErr.Line, scanErr.Column)
+			end := d.position(scanErr.Line, scanErr.Column+1)
+			d.diagnose(Range{Start: start, End: end}, SeverityError, "", scanErr.Message)
+		}
+	}
+
+	parser := components.InitParser(tokens)
+	statements, _ := parser.Parse()
+	for _, err := range parser.Errors() {
+		if parseErr, ok := err.(*components.ParseError); ok {
+			d.diagnose(d.tokenRange(parseErr.Token), SeverityError, "", parseErr.Message)
+		}
+	}
+	resolver := semantics.InitResolver()
+	if path := filePath(uri); path != "" {
+		// a new loader every time, the imported files may have changed since
+		resolver.SetModuleLoader(components.InitScriptLoader(components.SearchPath(os.Getenv("SCOOP_PATH"))), path)
+	}
+	for _, err := range resolver.Resolve(statements) {
+		if resolveErr, ok := err.(*semantics.ResolveError); ok {
+			d.diagnose(d.tokenRange(resolveErr.Token), SeverityError, "", resolveErr.Message)
+		}
+	}
+
+	// the linter needs a program without errors
+	if len(d.diagnostics) == 0 {
+		warnings, _ := components.Lint(text)
+		for _, warning := range warnings {
+			d.diagnose(d.tokenRange(warning.Token), SeverityWarning, warning.Rule, warning.Message)
+		}
+	}
+
+	d.references = semantics.InitReferenceIndex().Index(statements)
+	return d
+}
+
+// filePath returns the path of a file:// uri, "" for documents that are not files
+func filePath(uri string) string {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "file" {
+		return ""
+	}
+	return filepath.FromSlash(parsed.Path)
+}
+
+func (d *document) diagnose(at Range, severity int, code string, message string) {
+	d.diagnostics = append(d.diagnostics, Diagnostic{Range: at, Severity: severity, Code: code, Source: "scoop", Message: message})
+}
+
+// position converts a 1 based line and byte column into an LSP position
+func (d *document) position(line int, column int) Position {
+	if line < 1 || line > len(d.lines) {
+		return Position{Line: max(line-1, 0)}
+	}
+	text := d.lines[line-1]
+	column = min(column, len(text))
+	return Position{Line: line - 1, Character: utf16Length(text[:column])}
+}
+
+func (d *document) tokenRange(token semantics.Token) Range {
+	return Range{Start: d.position(token.Line, token.Column), End: d.position(token.Line, token.Column+len(token.Lexeme))}
+}
+
+// column converts an LSP position back into a 1 based line and byte column
+func (d *document) column(position Position) (int, int) {
+	if position.Line < 0 || position.Line >= len(d.lines) {
+		return position.Line + 1, 0
+	}
+	text := d.lines[position.Line]
+	units := 0
+	for offset, char := range text {
+		if units >= position.Character {
+			return position.Line + 1, offset
+		}
+		units += utf16RuneLength(char)
+	}
+	return position.Line + 1, len(text)
+}
+
+// referenceAt finds the variable name under the cursor, the position right after the
+// last character still counts so it works with the cursor at the end of a word
+func (d *document) referenceAt(position Position) *semantics.Reference {
+	line, column := d.column(position)
+	for _, reference := range d.references {
+		token := reference.Token
+		if token.Line == line && column >= token.Column && column <= token.Column+len(token.Lexeme) {
+			return reference
+		}
+	}
+	return nil
+}
+
+// referencesTo returns every occurrence of the same variable as reference
+func (d *document) referencesTo(reference *semantics.Reference, includeDeclaration bool) []*semantics.Reference {
+	found := []*semantics.Reference{}
+	for _, candidate := range d.references {
+		if candidate.Declares && !includeDeclaration {
+			continue
+		}
+		sameVariable := candidate.Declaration == reference.Declaration
+		if reference.Declaration == nil {
+			sameVariable = candidate.Declaration == nil && candidate.Token.Lexeme == reference.Token.Lexeme
+		}
+		if sameVariable {
+			found = append(found, candidate)
+		}
+	}
+	return found
+}
+
+func (d *document) hover(position Position) *Hover {
+	reference := d.referenceAt(position)
+	if reference == nil {
+		return nil
+	}
+
+	var contents string
+	if reference.Declaration == nil {
+		contents = "`" + reference.Token.Lexeme + "` is not declared in this file"
+	} else {
+		declaration := reference.Declaration.Name
+		source := strings.TrimSpace(d.lines[declaration.Line-1])
+		contents = "```scoop\n" + source + "\n```\n"
+		if doc := reference.Declaration.DocComment(); doc != "" {
+			contents += doc + "\n\n"
+		}
+		contents += "declared on line " + strconv.Itoa(declaration.Line)
+	}
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: contents}, Range: d.tokenRange(reference.Token)}
+}
+
+func (d *document) symbols() []DocumentSymbol {
+	symbols := []DocumentSymbol{}
+	for _, reference := range d.references {
+		if !reference.Declares {
+			continue
+		}
+		at := d.tokenRange(reference.Token)
+		line := strings.TrimSpace(d.lines[reference.Token.Line-1])
+		kind := SymbolVariable
+		if reference.Declaration != nil && reference.Declaration.Constant {
+			kind = SymbolConstant
+		}
+		symbols = append(symbols, DocumentSymbol{Name: reference.Token.Lexeme, Detail: line, Kind: kind, Range: at, SelectionRange: at})
+	}
+	return symbols
+}
+
+// completions offers the keywords and every variable declared in the document
+func (d *document) completions() []CompletionItem {
+	items := []CompletionItem{}
+	for _, keyword := range components.Keywords() {
+		items = append(items, CompletionItem{Label: keyword, Kind: CompletionKeyword})
+	}
+
+	names := map[string]string{"args": "command line arguments", "format": "format(template, args...) fills the {} of the template"}
+	for _, reference := range d.references {
+		if reference.Declares {
+			names[reference.Token.Lexeme] = "declared on line " + strconv.Itoa(reference.Token.Line)
+		}
+	}
+	sorted := []string{}
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		items = append(items, CompletionItem{Label: name, Kind: CompletionVariable, Detail: names[name]})
+	}
+	return items
+}
+
+// format returns the edit that replaces the whole document with its formatted text,
+// nothing when it is already formatted or can't be formatted
+func (d *document) format() []TextEdit {
+	formatted, err := components.InitFormatter().Format(d.text)
+	if err != nil || formatted == d.text {
+		return []TextEdit{}
+	}
+	last := len(d.lines) - 1
+	end := Position{Line: last, Character: utf16Length(d.lines[last])}
+	return []TextEdit{{Range: Range{Start: Position{}, End: end}, NewText: formatted}}
+}
+
+func (d *document) location(token semantics.Token) Location {
+	return Location{URI: d.uri, Range: d.tokenRange(token)}
+}
+
+func utf16Length(text string) int {
+	length := 0
+	for _, char := range text {
+		length += utf16RuneLength(char)
+	}
+	return length
+}
+
+func utf16RuneLength(char rune) int {
+	if char >= 0x10000 && char <= utf8.MaxRune {
+		return 2
+	}
+	return 1
+}
@@ -0,0 +1,246 @@
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Server is a Language Server Protocol server for scoop speaking JSON-RPC over a
+// pair of streams (normally stdin and stdout). Documents are synchronised in full
+// on every change and re-analysed straight away, which is cheap for scripts this size
+type Server struct {
+	reader    *bufio.Reader
+	writer    io.Writer
+	writeLock sync.Mutex
+	version   string
+	documents map[string]*document
+	shutdown  bool
+}
+
+func InitServer(in io.Reader, out io.Writer, version string) *Server {
+	return &Server{
+		reader:    bufio.NewReader(in),
+		writer:    out,
+		version:   version,
+		documents: map[string]*document{},
+	}
+}
+
+// Serve handles messages until the client sends "exit" or closes the stream.
+// It returns nil for an orderly exit, i.e. "shutdown" was requested first
+func (s *Server) Serve() error {
+	for {
+		request, err := s.read()
+		if err == io.EOF {
+			return errors.New("client closed the stream without exiting")
+		}
+		if malformed, isMalformed := err.(*malformedMessage); isMalformed {
+			// JSON-RPC answers a message whose id can't be read with "id": null
+			id := json.RawMessage("null")
+			s.respondError(&id, codeParseError, malformed.Error())
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if request.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+		s.handle(request)
+	}
+}
+
+// malformedMessage is a message body that is not a JSON-RPC message, the stream itself is
+// still in step so the server answers it and reads on
+type malformedMessage struct {
+	err error
+}
+
+func (e *malformedMessage) Error() string {
+	return "invalid message: " + e.err.Error()
+}
+
+// read returns the next message, each one is preceded by a Content-Length header.
+// Errors other than *malformedMessage mean the stream can't be read any further
+func (s *Server) read() (*message, error) {
+	headers, err := textproto.NewReader(s.reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length: %v", err)
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.reader, body); err != nil {
+		return nil, err
+	}
+
+	request := &message{}
+	if err := json.Unmarshal(body, request); err != nil {
+		return nil, &malformedMessage{err}
+	}
+	return request, nil
+}
+
+func (s *Server) write(response *message) {
+	response.JSONRPC = "2.0"
+	body, err := json.Marshal(response)
+	if err != nil {
+		return
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+	fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *Server) respond(id *json.RawMessage, result interface{}) {
+	if result == nil {
+		// a request must always get a result, null included
+		result = json.RawMessage("null")
+	}
+	s.write(&message{ID: id, Result: result})
+}
+
+func (s *Server) respondError(id *json.RawMessage, code int, text string) {
+	s.write(&message{ID: id, Error: &responseError{Code: code, Message: text}})
+}
+
+func (s *Server) notify(method string, params interface{}) {
+	body, err := json.Marshal(params)
+	if err != nil {
+		return
+	}
+	s.write(&message{Method: method, Params: body})
+}
+
+func (s *Server) handle(request *message) {
+	switch request.Method {
+	case "initialize":
+		result := InitializeResult{Capabilities: ServerCapabilities{
+			TextDocumentSync:           1, // full
+			HoverProvider:              true,
+			DefinitionProvider:         true,
+			ReferencesProvider:         true,
+			DocumentSymbolProvider:     true,
+			CompletionProvider:         map[string]interface{}{},
+			DocumentFormattingProvider: true,
+		}}
+		result.ServerInfo.Name = "scoop"
+		result.ServerInfo.Version = s.version
+		s.respond(request.ID, result)
+	case "initialized":
+	case "shutdown":
+		s.shutdown = true
+		s.respond(request.ID, nil)
+	case "textDocument/didOpen":
+		params := &DidOpenTextDocumentParams{}
+		if s.decode(request, params) {
+			s.update(params.TextDocument.URI, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		params := &DidChangeTextDocumentParams{}
+		if s.decode(request, params) && len(params.ContentChanges) > 0 {
+			s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		params := &DidCloseTextDocumentParams{}
+		if s.decode(request, params) {
+			delete(s.documents, params.TextDocument.URI)
+			s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: params.TextDocument.URI, Diagnostics: []Diagnostic{}})
+		}
+	case "textDocument/hover":
+		params := &TextDocumentPositionParams{}
+		if document := s.document(request, params, &params.TextDocument); document != nil {
+			if hover := document.hover(params.Position); hover != nil {
+				s.respond(request.ID, hover)
+			} else {
+				s.respond(request.ID, nil)
+			}
+		}
+	case "textDocument/definition":
+		params := &TextDocumentPositionParams{}
+		if document := s.document(request, params, &params.TextDocument); document != nil {
+			reference := document.referenceAt(params.Position)
+			if reference == nil || reference.Declaration == nil {
+				s.respond(request.ID, nil)
+				return
+			}
+			s.respond(request.ID, document.location(reference.Declaration.Name))
+		}
+	case "textDocument/references":
+		params := &ReferenceParams{}
+		if document := s.document(request, params, &params.TextDocument); document != nil {
+			locations := []Location{}
+			if reference := document.referenceAt(params.Position); reference != nil {
+				for _, found := range document.referencesTo(reference, params.Context.IncludeDeclaration) {
+					locations = append(locations, document.location(found.Token))
+				}
+			}
+			s.respond(request.ID, locations)
+		}
+	case "textDocument/documentSymbol":
+		params := &DocumentSymbolParams{}
+		if document := s.document(request, params, &params.TextDocument); document != nil {
+			s.respond(request.ID, document.symbols())
+		}
+	case "textDocument/completion":
+		params := &TextDocumentPositionParams{}
+		if document := s.document(request, params, &params.TextDocument); document != nil {
+			s.respond(request.ID, document.completions())
+		}
+	case "textDocument/formatting":
+		params := &DocumentFormattingParams{}
+		if document := s.document(request, params, &params.TextDocument); document != nil {
+			s.respond(request.ID, document.format())
+		}
+	default:
+		// notifications we don't know ("$/..." included) are ignored, requests are answered
+		if request.ID != nil {
+			s.respondError(request.ID, codeMethodNotFound, "method not supported: "+request.Method)
+		}
+	}
+}
+
+// decode reads the params of the request, answering with an error when they are invalid
+func (s *Server) decode(request *message, params interface{}) bool {
+	if err := json.Unmarshal(request.Params, params); err != nil {
+		if request.ID != nil {
+			s.respondError(request.ID, codeInvalidParams, err.Error())
+		}
+		return false
+	}
+	return true
+}
+
+// document decodes the params and returns the open document they refer to
+func (s *Server) document(request *message, params interface{}, identifier *TextDocumentIdentifier) *document {
+	if !s.decode(request, params) {
+		return nil
+	}
+	document, found := s.documents[identifier.URI]
+	if !found {
+		s.respondError(request.ID, codeInvalidParams, "document is not open: "+identifier.URI)
+		return nil
+	}
+	return document
+}
+
+func (s *Server) update(uri string, text string) {
+	document := analyse(uri, strings.ReplaceAll(text, "\r\n", "\n"))
+	s.documents[uri] = document
+	s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: document.diagnostics})
+}
@@ -0,0 +1,102 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func frame(body string) string {
+	return fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+// responses splits what the server wrote into its messages, along with their JSON bodies
+// since a null id and a missing one decode the same
+func responses(t *testing.T, out *bytes.Buffer) ([]*message, []map[string]json.RawMessage) {
+	t.Helper()
+	reader := bufio.NewReader(out)
+	messages := []*message{}
+	bodies := []map[string]json.RawMessage{}
+	for {
+		headers, err := textproto.NewReader(reader).ReadMIMEHeader()
+		if err == io.EOF {
+			return messages, bodies
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		length, _ := strconv.Atoi(headers.Get("Content-Length"))
+		body := make([]byte, length)
+		if _, err := io.ReadFull(reader, body); err != nil {
+			t.Fatal(err)
+		}
+		received := &message{}
+		if err := json.Unmarshal(body, received); err != nil {
+			t.Fatal(err)
+		}
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(body, &fields); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, received)
+		bodies = append(bodies, fields)
+	}
+}
+
+// a body that is not a JSON-RPC message is answered with an error and the session goes on
+func TestServeAnswersMalformedMessages(t *testing.T) {
+	malformed := []string{
+		`{"jsonrpc": "2.0", "id": 1, "method": `,
+		`{"jsonrpc": "2.0", "id": 2, "method": 5}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "initialize", "params": 1, "error": "none"}`,
+		`[1, 2]`,
+	}
+	in := &strings.Builder{}
+	for _, body := range malformed {
+		in.WriteString(frame(body))
+	}
+	in.WriteString(frame(`{"jsonrpc": "2.0", "id": 10, "method": "initialize", "params": {}}`))
+	in.WriteString(frame(`{"jsonrpc": "2.0", "id": 11, "method": "shutdown"}`))
+	in.WriteString(frame(`{"jsonrpc": "2.0", "method": "exit"}`))
+
+	out := &bytes.Buffer{}
+	if err := InitServer(strings.NewReader(in.String()), out, "test").Serve(); err != nil {
+		t.Fatalf("want an orderly exit, got %v", err)
+	}
+
+	messages, bodies := responses(t, out)
+	if len(messages) != len(malformed)+2 {
+		t.Fatalf("want %v responses, got %v", len(malformed)+2, len(messages))
+	}
+	for i, body := range malformed {
+		if messages[i].Error == nil || messages[i].Error.Code != codeParseError {
+			t.Errorf("%v: want a parse error, got %+v", body, messages[i])
+		}
+		// the id can't be read from a malformed message, JSON-RPC wants it present and null
+		if id, found := bodies[i]["id"]; !found || string(id) != "null" {
+			t.Errorf("%v: want \"id\": null in the response, got %v", body, bodies[i])
+		}
+	}
+	if initialize := messages[len(malformed)]; initialize.Error != nil || string(*initialize.ID) != "10" {
+		t.Errorf("want initialize to succeed after the malformed messages, got %+v", initialize)
+	}
+}
+
+func TestServeStopsOnABrokenStream(t *testing.T) {
+	out := &bytes.Buffer{}
+	err := InitServer(strings.NewReader("Content-Length: many\r\n\r\n{}"), out, "test").Serve()
+	if err == nil || !strings.Contains(err.Error(), "invalid Content-Length") {
+		t.Errorf("want the server to stop on a bad header, got %v", err)
+	}
+
+	err = InitServer(strings.NewReader(frame(`{"jsonrpc": "2.0", "method": "exit"}`)[:30]), out, "test").Serve()
+	if err == nil {
+		t.Error("want the server to stop on a truncated body")
+	}
+}
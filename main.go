@@ -2,18 +2,35 @@ package main
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"os"
+	"path/filepath"
 	"scoop/components"
+	"scoop/manifest"
 	"scoop/semantics"
+	"strings"
 )
 
 var HadError bool = false
 var HadRuntimeError bool = false
 
+// Verbose turns on diagnostic logging such as the source being scanned
+var Verbose bool = false
+
 var interpreter semantics.Interpreter = *semantics.InitInterpreter()
 
+var highlighter *components.Highlighter = components.InitHighlighter(false)
+
+// modules compiles the scripts named by imports, for the resolver and the interpreter alike
+var modules *components.ScriptLoader = components.InitScriptLoader(nil)
+
+// scriptPath is the absolute path of the script being run, its imports are relative to it
+var scriptPath string
+
 type Scoop struct {
 }
 
@@ -21,67 +38,445 @@ func (s *Scoop) runPrompt() {
 	reader := bufio.NewScanner(os.Stdin)
 	for {
 		fmt.Print("\nscoop>> ")
-		reader.Scan()
+		if !reader.Scan() {
+			return
+		}
 		line := reader.Text()
+		if highlighter.Enabled() {
+			// move back over the line that was just typed and redraw it with colours
+			fmt.Print("\x1b[1A\r\x1b[2K" + "scoop>> " + highlighter.Highlight(line) + "\n")
+		}
 		s.run(line)
 		HadError = false
+		HadRuntimeError = false
 	}
 }
 
-func (s *Scoop) runFile(path string) {
+// runFile streams the script through the scanner and parser instead of reading it whole,
+// generated scripts can be many megabytes
+func (s *Scoop) runFile(path string) int {
 	// log.Println("running file...")
-	bytes, err := os.ReadFile(path)
+	file, err := os.Open(path)
 	if err != nil {
-		log.Fatal(err)
-		os.Exit(65)
+		log.Println(err)
+		return 66
 	}
-	s.run(string(bytes))
+	defer file.Close()
+	setScriptPath(path)
+	s.runStream(file, sourceOf(path))
+	return s.exitStatus()
+}
+
+// setScriptPath makes the imports of the script relative to its directory
+func setScriptPath(path string) {
+	if absolute, err := filepath.Abs(path); err == nil {
+		scriptPath = absolute
+		interpreter.SetScriptPath(absolute)
+	}
+}
+
+// sourceOf reads a script only when an error needs to echo its code, and only once
+func sourceOf(path string) func() string {
+	var source *string
+	return func() string {
+		if source == nil {
+			bytes, _ := os.ReadFile(path)
+			text := string(bytes)
+			source = &text
+		}
+		return *source
+	}
+}
+
+// exitStatus maps the errors reported so far to the process exit status
+func (s *Scoop) exitStatus() int {
 	if HadError {
-		log.Fatal("Errors occured while running...")
-		os.Exit(65)
+		log.Println("Errors occured while running...")
+		return 65
 	}
 
 	if HadRuntimeError {
-		log.Fatal("Runtime Errors occured while running...")
-		os.Exit(70)
+		log.Println("Runtime Errors occured while running...")
+		return 70
 	}
-
+	return 0
 }
 
 func (s *Scoop) run(source string) {
-	log.Printf("Scanning Args: [ %v ]", source)
+	debugf("Scanning Args: [ %v ]", source)
+	s.runStream(strings.NewReader(source), func() string { return source })
+}
+
+// runStream runs the script read from reader, source is only called to show the code of an error
+func (s *Scoop) runStream(reader io.Reader, source func() string) {
+	s.execute(s.parseStream(reader, source), source)
+}
+
+// execute runs the program unless an error was reported while parsing it,
+// scripts and programs decoded from JSON both go through it
+func (s *Scoop) execute(statement []semantics.Statement, source func() string) {
+	if HadError {
+		return
+	}
+
+	// interpreter := semantics.InitInterpreter()
+
+	if err := interpreter.Interprete(statement); err != nil {
+		if runtimeErr, ok := err.(*semantics.RuntimeError); ok {
+			RuntimeError(runtimeErr)
+			printSnippet(source(), runtimeErr.Token.Line)
+		} else if limitErr, ok := err.(*semantics.LimitError); ok {
+			LimitExceeded(limitErr)
+			printSnippet(source(), limitErr.Line)
+		} else {
+			log.Println(err)
+		}
+	}
+
+	// printer := semantics.InitAbstractSyntaxTreePrinter()
+
+	// fmt.Printf("Expression Tree : %v", printer.Print(expression))
+}
 
-	scanner := components.InitScanner(source)
-	tokens := scanner.ScanTokens()
-	parser := components.InitParser(tokens)
+// parse scans and parses the source, reporting every error found along the way
+func (s *Scoop) parse(source string) []semantics.Statement {
+	return s.parseStream(strings.NewReader(source), func() string { return source })
+}
+
+// parseStream parses tokens as the scanner reads them, the scan errors are reported
+// once the parser is done since they are only known by then
+func (s *Scoop) parseStream(reader io.Reader, source func() string) []semantics.Statement {
+	scanner := components.InitStreamScanner(reader)
+	parser := components.InitStreamParser(scanner)
 
 	// expression, err := parser.Parse()
 	statement, err := parser.Parse()
+	for _, scanErr := range scanner.Errors() {
+		if scanErr, ok := scanErr.(*components.ScanError); ok {
+			Error(scanErr.Line, scanErr.Message)
+			printSnippet(source(), scanErr.Line)
+		}
+	}
 	// log.Println("Done with parsing ")
 	// fmt.Print(fmt.Sprintf("\nstatements from Parse : %+v", statement))
 
 	if err != nil {
-		log.Print(fmt.Sprintf("Error from Parser  : %v", err.Error()))
+		for _, parseErr := range parser.Errors() {
+			if parseErr, ok := parseErr.(*components.ParseError); ok {
+				PrintError(parseErr.Token, parseErr.Message)
+				printSnippet(source(), parseErr.Token.Line)
+			}
+		}
 	}
+	return statement
+}
 
-	if HadError {
-		return
+// resolve runs the static checks over a parsed program, reporting every error found
+func (s *Scoop) resolve(source func() string, statements []semantics.Statement) {
+	resolver := semantics.InitResolver()
+	resolver.SetModuleLoader(modules, scriptPath)
+	for _, resolveErr := range resolver.Resolve(statements) {
+		if resolveErr, ok := resolveErr.(*semantics.ResolveError); ok {
+			PrintError(resolveErr.Token, resolveErr.Message)
+			printSnippet(source(), resolveErr.Token.Line)
+		}
 	}
+}
 
-	// interpreter := semantics.InitInterpreter()
+// printAst parses the file and prints the whole program,
+// format is either "sexpr" (indented S-expressions) or "json"
+func (s *Scoop) printAst(path string, format string) {
+	statements := s.parseFile(path)
 
-	interpreter.Interprete(statement)
+	switch format {
+	case "sexpr":
+		printer := semantics.InitAbstractSyntaxTreePrinter()
+		fmt.Print(printer.PrintProgram(statements))
+	case "json":
+		encoded, err := semantics.InitJSONEncoder().Encode(statements)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(string(encoded))
+	default:
+		log.Fatalf("Unknown ast format '%v', expected sexpr or json", format)
+	}
+}
 
-	// printer := semantics.InitAbstractSyntaxTreePrinter()
+// printGraph parses the file and prints it as a Graphviz DOT graph
+func (s *Scoop) printGraph(path string, scopes bool) {
+	statements := s.parseFile(path)
+	fmt.Print(semantics.InitGraphPrinter(scopes).Print(statements))
+}
 
-	// fmt.Printf("Expression Tree : %v", printer.Print(expression))
+// formatFiles runs the formatter over every file. By default the result is printed,
+// with check it only lists the files that are not formatted and with write it rewrites them.
+// It returns false when a file could not be formatted or, with check, needs formatting
+func (s *Scoop) formatFiles(paths []string, check bool, write bool) bool {
+	formatter := components.InitFormatter()
+	ok := true
+	for _, path := range paths {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			log.Println(err)
+			ok = false
+			continue
+		}
+
+		source := string(bytes)
+		formatted, err := formatter.Format(source)
+		if err != nil {
+			fmt.Print(path + ": ")
+			if parseErr, isParseErr := err.(*components.ParseError); isParseErr {
+				PrintError(parseErr.Token, parseErr.Message)
+			} else if scanErr, isScanErr := err.(*components.ScanError); isScanErr {
+				Error(scanErr.Line, scanErr.Message)
+			} else {
+				fmt.Println(err)
+			}
+			ok = false
+			continue
+		}
+
+		switch {
+		case check:
+			if formatted != source {
+				fmt.Println(path)
+				ok = false
+			}
+		case write:
+			if formatted != source {
+				if err := os.WriteFile(path, []byte(formatted), 0644); err != nil {
+					log.Println(err)
+					ok = false
+				}
+			}
+		default:
+			fmt.Print(formatted)
+		}
+	}
+	return ok
+}
+
+// writeDocs documents every script given, directories are searched for *.scoop files.
+// Source links are relative to the output file so the pages can be moved along with the scripts
+func (s *Scoop) writeDocs(paths []string, format string, out string) int {
+	scripts := []string{}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Println(err)
+			return 66
+		}
+		if !info.IsDir() {
+			scripts = append(scripts, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err == nil && !entry.IsDir() && filepath.Ext(file) == ".scoop" {
+				scripts = append(scripts, file)
+			}
+			return err
+		})
+		if err != nil {
+			log.Println(err)
+			return 66
+		}
+	}
+
+	pages := []*components.DocPage{}
+	for _, path := range scripts {
+		bytes, err := os.ReadFile(path)
+		if err != nil {
+			log.Println(err)
+			return 66
+		}
+		page, err := components.Document(path, string(bytes))
+		if err != nil {
+			fmt.Print(path + ": ")
+			s.parse(string(bytes))
+			return 65
+		}
+		if out != "" {
+			page.Link = relativeLink(filepath.Dir(out), path)
+		}
+		pages = append(pages, page)
+	}
+
+	rendered := components.RenderMarkdown(pages)
+	if format == "html" {
+		rendered = components.RenderHTML(pages)
+	}
+	if out == "" {
+		fmt.Print(rendered)
+		return 0
+	}
+	if err := os.WriteFile(out, []byte(rendered), 0644); err != nil {
+		log.Println(err)
+		return 1
+	}
+	return 0
+}
+
+// lockDependencies writes the scoop.lock of the package in dir, copying the dependencies
+// to vendor/ first when vendor is set. Dependencies no script of the package imports are
+// reported, they are kept since scoop.mod is only ever edited by hand
+func (s *Scoop) lockDependencies(dir string, vendor bool) int {
+	m, err := manifest.Load(dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Println("No " + manifest.FileName + " in " + dir)
+		return 66
+	}
+	if err != nil {
+		log.Println(err)
+		return 65
+	}
+
+	var lock manifest.Lock
+	if vendor {
+		lock, err = m.Vendor()
+	} else {
+		lock, err = m.Tidy()
+	}
+	if err != nil {
+		log.Println(err)
+		return 1
+	}
+
+	imported := importedDependencies(m.Dir)
+	for _, dependency := range m.Dependencies {
+		fmt.Printf("%v %v\n", dependency.Name, lock[dependency.Name])
+		if !imported[dependency.Name] {
+			fmt.Println(highlighter.Warning(fmt.Sprintf("%v:%v: '%v' is not imported by any script of the package", filepath.Join(m.Dir, manifest.FileName), dependency.Line, dependency.Name)))
+		}
+	}
+	return 0
+}
+
+// importedDependencies returns the first segment of every import path in the scripts of
+// the package, vendor/ is left out. Scripts that don't parse are skipped
+func importedDependencies(dir string) map[string]bool {
+	imported := map[string]bool{}
+	filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() && path == filepath.Join(dir, manifest.VendorDir) {
+			return filepath.SkipDir
+		}
+		if entry.IsDir() || filepath.Ext(path) != ".scoop" {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return nil
+		}
+		defer file.Close()
+		statements, _ := components.InitStreamParser(components.InitStreamScanner(file)).Parse()
+		for _, statement := range statements {
+			if declaration, ok := statement.(*semantics.Import); ok {
+				written, _ := declaration.Path.Literal.(string)
+				name, _, _ := strings.Cut(written, "/")
+				imported[name] = true
+			}
+		}
+		return nil
+	})
+	return imported
+}
+
+func relativeLink(from string, path string) string {
+	if absolute, err := filepath.Abs(path); err == nil {
+		if base, err := filepath.Abs(from); err == nil {
+			if relative, err := filepath.Rel(base, absolute); err == nil {
+				return filepath.ToSlash(relative)
+			}
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// parseFile reads and parses a script for the tooling commands, exiting on any error
+func (s *Scoop) parseFile(path string) []semantics.Statement {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	statements := s.parse(string(bytes))
+	if HadError {
+		os.Exit(65)
+	}
+	return statements
+}
+
+// runAstFile executes a program previously written by "scoop ast --format=json"
+func (s *Scoop) runAstFile(path string) int {
+	bytes, err := os.ReadFile(path)
+	if err != nil {
+		log.Println(err)
+		return 66
+	}
+
+	statements, err := semantics.InitJSONDecoder().Decode(bytes)
+	if err != nil {
+		log.Println(err)
+		return 65
+	}
+
+	// imports are resolved next to the syntax tree, it is usually saved beside its script.
+	// There is no source to show snippets of
+	setScriptPath(path)
+	s.execute(statements, func() string { return "" })
+	return s.exitStatus()
 }
 
 func Report(line int, where string, message string) {
-	fmt.Printf("[line %v] Error %v : %v", line, where, message)
+	fmt.Println(highlighter.Error(fmt.Sprintf("[line %v] Error%v : %v", line, where, message)))
 	HadError = true
 }
 
+func RuntimeError(err *semantics.RuntimeError) {
+	fmt.Println(highlighter.Error(fmt.Sprintf("[line %v] %v", err.Token.Line, err.Message)))
+	HadRuntimeError = true
+}
+
+// LimitExceeded reports a script stopped by one of the interpreter's limits, it exits like a runtime error
+func LimitExceeded(err *semantics.LimitError) {
+	fmt.Println(highlighter.Error(fmt.Sprintf("[line %v] %v", err.Line, err.Message)))
+	HadRuntimeError = true
+}
+
+// printSnippet echoes the offending line of source under an error, programs decoded
+// from JSON have no source to show
+func printSnippet(source string, line int) {
+	if source == "" {
+		return
+	}
+	if snippet := highlighter.Snippet(source, line); snippet != "" {
+		fmt.Println(snippet)
+	}
+}
+
+func debugf(format string, v ...interface{}) {
+	if Verbose {
+		log.Printf(format, v...)
+	}
+}
+
+// colorEnabled reports whether ANSI colours should be written to the file,
+// they are off when it is not a terminal or when NO_COLOR is set (https://no-color.org)
+func colorEnabled(file *os.File) bool {
+	if _, found := os.LookupEnv("NO_COLOR"); found {
+		return false
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
 func Error(line int, message string) {
 	Report(line, "", message)
 }
@@ -90,19 +485,6 @@ func PrintError(token semantics.Token, message string) {
 	if token.TokenType == semantics.EOF {
 		Report(token.Line, " at end", message)
 	} else {
-		Report(token.Line, " at "+token.Lexeme+"'", message)
-	}
-}
-
-func main() {
-	runner := Scoop{}
-	log.Println("Starting Scoop Interpreter...")
-	args := os.Args[1:]
-	if len(args) > 1 {
-		log.Println("Usage : Scoop [script]...")
-	} else if len(args) == 1 {
-		runner.runFile(args[0])
-	} else {
-		runner.runPrompt()
+		Report(token.Line, " at '"+token.Lexeme+"'", message)
 	}
 }
@@ -0,0 +1,240 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileName is the manifest of a package, it sits at the root of the package directory:
+//
+//	// comments start with two slashes
+//	package reports
+//	require strings ../shared/strings
+//	require charts archives/charts-1.2.tar.gz
+//
+// A dependency is a local directory or a .tar.gz, .tgz or .zip archive, relative paths
+// are relative to the package directory. Scripts of the package import a dependency's
+// files by its name, import "strings/text.scoop" as text;
+const FileName = "scoop.mod"
+
+// LockFileName records the checksum of every dependency, written by "scoop mod tidy"
+const LockFileName = "scoop.lock"
+
+// VendorDir holds a copy of every dependency, written by "scoop mod vendor"
+const VendorDir = "vendor"
+
+type Dependency struct {
+	Name   string
+	Source string
+	// the line of the manifest it is required on
+	Line int
+}
+
+// IsArchive reports whether the dependency is an archive rather than a directory
+func (d *Dependency) IsArchive() bool {
+	for _, extension := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(d.Source, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+type Manifest struct {
+	// Dir is the package directory, the one holding scoop.mod
+	Dir          string
+	Package      string
+	Dependencies []*Dependency
+	// the vendored dependencies already checked against scoop.lock
+	verified map[string]bool
+}
+
+// ManifestError is a line of scoop.mod that could not be understood
+type ManifestError struct {
+	Path    string
+	Line    int
+	Message string
+}
+
+func (e *ManifestError) Error() string {
+	return fmt.Sprintf("%v:%v: %v", e.Path, e.Line, e.Message)
+}
+
+// Parse reads the manifest found at path
+func Parse(path string, data []byte) (*Manifest, error) {
+	m := &Manifest{Dir: filepath.Dir(path), verified: map[string]bool{}}
+	for number, line := range strings.Split(string(data), "\n") {
+		if comment := strings.Index(line, "//"); comment >= 0 {
+			line = line[:comment]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		problem := func(message string) error {
+			return &ManifestError{Path: path, Line: number + 1, Message: message}
+		}
+		switch fields[0] {
+		case "package":
+			if len(fields) != 2 {
+				return nil, problem("expect 'package name'")
+			}
+			if m.Package != "" {
+				return nil, problem("the package is already named '" + m.Package + "'")
+			}
+			m.Package = fields[1]
+		case "require":
+			if len(fields) != 3 {
+				return nil, problem("expect 'require name path'")
+			}
+			if !validName(fields[1]) {
+				return nil, problem("invalid dependency name '" + fields[1] + "', use letters, digits, '_' and '-'")
+			}
+			if m.Dependency(fields[1]) != nil {
+				return nil, problem("'" + fields[1] + "' is already required")
+			}
+			m.Dependencies = append(m.Dependencies, &Dependency{Name: fields[1], Source: fields[2], Line: number + 1})
+		default:
+			return nil, problem("unknown directive '" + fields[0] + "'")
+		}
+	}
+
+	if m.Package == "" {
+		return nil, &ManifestError{Path: path, Line: 1, Message: "missing 'package name'"}
+	}
+	sort.Slice(m.Dependencies, func(i, j int) bool {
+		return m.Dependencies[i].Name < m.Dependencies[j].Name
+	})
+	return m, nil
+}
+
+func validName(name string) bool {
+	for _, character := range name {
+		switch {
+		case character >= 'a' && character <= 'z', character >= 'A' && character <= 'Z',
+			character >= '0' && character <= '9', character == '_', character == '-':
+		default:
+			return false
+		}
+	}
+	return name != ""
+}
+
+// Load reads the scoop.mod of the package directory
+func Load(dir string) (*Manifest, error) {
+	path := filepath.Join(dir, FileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Find returns the manifest of the package dir belongs to, looking in dir and then in
+// every directory above it. It returns nil when dir is not in a package
+func Find(dir string) (*Manifest, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		m, err := Load(dir)
+		if err == nil {
+			return m, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+func (m *Manifest) Dependency(name string) *Dependency {
+	for _, dependency := range m.Dependencies {
+		if dependency.Name == name {
+			return dependency
+		}
+	}
+	return nil
+}
+
+// SourcePath is where the dependency comes from on disk
+func (m *Manifest) SourcePath(dependency *Dependency) string {
+	if filepath.IsAbs(dependency.Source) {
+		return dependency.Source
+	}
+	return filepath.Join(m.Dir, filepath.FromSlash(dependency.Source))
+}
+
+// VendorPath is where "scoop mod vendor" copies the dependency
+func (m *Manifest) VendorPath(dependency *Dependency) string {
+	return filepath.Join(m.Dir, VendorDir, dependency.Name)
+}
+
+// Resolve finds the file an import path names when it starts with the name of a
+// dependency. The vendored copy is used when there is one, otherwise the source
+// directory, either must still match scoop.lock. found is false when the path does
+// not start with a dependency name. The rest of the path has to stay inside the
+// dependency, ".." can't climb out of it
+func (m *Manifest) Resolve(path string) (resolved string, found bool, err error) {
+	name, rest, _ := strings.Cut(filepath.ToSlash(path), "/")
+	dependency := m.Dependency(name)
+	if dependency == nil {
+		return "", false, nil
+	}
+	rest = filepath.ToSlash(filepath.Clean(filepath.FromSlash(rest)))
+	if filepath.IsAbs(rest) || rest == ".." || strings.HasPrefix(rest, "../") {
+		return "", true, fmt.Errorf("Import '%v' is outside of dependency '%v'.", path, name)
+	}
+
+	vendored := m.VendorPath(dependency)
+	if info, err := os.Stat(vendored); err == nil && info.IsDir() {
+		if err := m.verify(dependency, vendored, "vendor"); err != nil {
+			return "", true, err
+		}
+		return filepath.Join(vendored, filepath.FromSlash(rest)), true, nil
+	}
+	if dependency.IsArchive() {
+		return "", true, fmt.Errorf("Dependency '%v' is an archive, run 'scoop mod vendor' in %v to unpack it.", name, m.Dir)
+	}
+	source := m.SourcePath(dependency)
+	if err := m.verify(dependency, source, "tidy"); err != nil {
+		return "", true, err
+	}
+	return filepath.Join(source, filepath.FromSlash(rest)), true, nil
+}
+
+// verify checks the directory a dependency is imported from against its checksum in
+// scoop.lock, command is the "scoop mod" command that brings them back in line
+func (m *Manifest) verify(dependency *Dependency, dir string, command string) error {
+	if m.verified[dependency.Name] {
+		return nil
+	}
+	lock, err := ReadLock(m.Dir)
+	if err != nil {
+		return err
+	}
+	expected, found := lock[dependency.Name]
+	if !found {
+		return fmt.Errorf("Dependency '%v' is missing from %v, run 'scoop mod tidy'.", dependency.Name, LockFileName)
+	}
+	actual, err := Checksum(dir)
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return fmt.Errorf("%v does not match its checksum in %v, run 'scoop mod %v'.", dir, LockFileName, command)
+	}
+	m.verified[dependency.Name] = true
+	return nil
+}
@@ -0,0 +1,285 @@
+package manifest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	m, err := Parse("pkg/scoop.mod", []byte(`// reports
+package reports
+require strings ../shared/strings // local
+require charts archives/charts-1.2.tar.gz
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Dir != "pkg" || m.Package != "reports" {
+		t.Errorf("got dir %q and package %q", m.Dir, m.Package)
+	}
+	if len(m.Dependencies) != 2 || m.Dependencies[0].Name != "charts" || m.Dependencies[1].Name != "strings" {
+		t.Fatalf("want charts and strings sorted by name, got %+v", m.Dependencies)
+	}
+	if charts := m.Dependencies[0]; !charts.IsArchive() || charts.Line != 4 {
+		t.Errorf("want charts to be an archive required on line 4, got %+v", charts)
+	}
+	if local := m.Dependency("strings"); local.IsArchive() || local.Source != "../shared/strings" {
+		t.Errorf("want strings to be the directory ../shared/strings, got %+v", local)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		line   int
+		want   string
+	}{
+		{"require a ../a\n", 1, "missing 'package name'"},
+		{"package a b\n", 1, "expect 'package name'"},
+		{"package a\npackage b\n", 2, "already named 'a'"},
+		{"package a\nrequire b\n", 2, "expect 'require name path'"},
+		{"package a\nrequire b/c ../c\n", 2, "invalid dependency name"},
+		{"package a\nrequire b ../b\nrequire b ../c\n", 3, "'b' is already required"},
+		{"package a\nreplace b ../b\n", 2, "unknown directive 'replace'"},
+	}
+	for _, test := range tests {
+		_, err := Parse("scoop.mod", []byte(test.source))
+		manifestErr, ok := err.(*ManifestError)
+		if !ok {
+			t.Errorf("%q: want a ManifestError, got %v", test.source, err)
+			continue
+		}
+		if manifestErr.Line != test.line || !strings.Contains(manifestErr.Message, test.want) {
+			t.Errorf("%q: got %v, want line %v mentioning %q", test.source, err, test.line, test.want)
+		}
+	}
+}
+
+// writeFiles creates the files under dir, name -> content
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func load(t *testing.T, dir string) *Manifest {
+	t.Helper()
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestResolveChecksDirectoriesAgainstTheLock(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"app/scoop.mod":          "package app\nrequire strings ../shared\n",
+		"app/sub/main.scoop":     `import "strings/text.scoop" as text;`,
+		"shared/text.scoop":      "export var name = 1;\n",
+		"shared/more/note.scoop": "var note = 2;\n",
+	})
+	app := filepath.Join(root, "app")
+
+	_, _, err := load(t, app).Resolve("strings/text.scoop")
+	if err == nil || !strings.Contains(err.Error(), "run 'scoop mod tidy'") {
+		t.Fatalf("want a dependency missing from the lock to ask for tidy, got %v", err)
+	}
+
+	m, err := Find(filepath.Join(app, "sub"))
+	if err != nil || m == nil {
+		t.Fatalf("want Find to reach the manifest from a subdirectory, got %v %v", m, err)
+	}
+	lock, err := m.Tidy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	written, err := ReadLock(app)
+	if err != nil || written["strings"] != lock["strings"] || !strings.HasPrefix(lock["strings"], "h1:") {
+		t.Fatalf("want scoop.lock to hold %v, got %v %v", lock, written, err)
+	}
+
+	resolved, found, err := load(t, app).Resolve("strings/text.scoop")
+	if err != nil || !found || resolved != filepath.Join(root, "shared", "text.scoop") {
+		t.Fatalf("got %v %v %v", resolved, found, err)
+	}
+	if _, found, err := load(t, app).Resolve("other/text.scoop"); found || err != nil {
+		t.Errorf("want a path outside the dependencies not to be found, got %v %v", found, err)
+	}
+	for _, escape := range []string{"strings/../app/sub/main.scoop", "strings/more/../../../etc/x.scoop", "strings/.."} {
+		if resolved, found, err := load(t, app).Resolve(escape); !found || err == nil || !strings.Contains(err.Error(), "outside of dependency 'strings'") {
+			t.Errorf("%v: want a path climbing out of the dependency to fail, got %v %v %v", escape, resolved, found, err)
+		}
+	}
+	resolved, _, err = load(t, app).Resolve("strings/more/../text.scoop")
+	if err != nil || resolved != filepath.Join(root, "shared", "text.scoop") {
+		t.Errorf("want .. inside the dependency to be cleaned, got %v %v", resolved, err)
+	}
+
+	writeFiles(t, root, map[string]string{"shared/more/note.scoop": "var note = 3;\n"})
+	_, _, err = load(t, app).Resolve("strings/text.scoop")
+	if err == nil || !strings.Contains(err.Error(), "does not match its checksum") || !strings.Contains(err.Error(), "run 'scoop mod tidy'") {
+		t.Errorf("want a changed dependency to ask for tidy, got %v", err)
+	}
+}
+
+func TestVendor(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"app/scoop.mod":     "package app\nrequire strings ../shared\nrequire charts charts-1.2.tar.gz\nrequire maps maps.zip\n",
+		"shared/text.scoop": "export var name = 1;\n",
+	})
+	app := filepath.Join(root, "app")
+	writeTarGz(t, filepath.Join(app, "charts-1.2.tar.gz"), map[string]string{
+		"charts-1.2/bar.scoop":     "var bar = 1;\n",
+		"charts-1.2/lib/pie.scoop": "var pie = 2;\n",
+	})
+	writeZip(t, filepath.Join(app, "maps.zip"), map[string]string{
+		"world.scoop":     "var world = 1;\n",
+		"lib/north.scoop": "var north = 2;\n",
+	})
+
+	if _, _, err := load(t, app).Resolve("charts/bar.scoop"); err == nil || !strings.Contains(err.Error(), "'scoop mod vendor'") {
+		t.Errorf("want an unvendored archive to ask for vendor, got %v", err)
+	}
+
+	tidied, err := load(t, app).Tidy()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock, err := load(t, app).Vendor()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, checksum := range tidied {
+		if lock[name] != checksum {
+			t.Errorf("%v: tidy locked %v but vendor %v", name, checksum, lock[name])
+		}
+	}
+
+	for _, path := range []string{"strings/text.scoop", "charts/bar.scoop", "charts/lib/pie.scoop", "maps/world.scoop", "maps/lib/north.scoop"} {
+		resolved, _, err := load(t, app).Resolve(path)
+		if err != nil {
+			t.Errorf("%v: %v", path, err)
+			continue
+		}
+		if want := filepath.Join(app, VendorDir, filepath.FromSlash(path)); resolved != want {
+			t.Errorf("%v: resolved to %v, want %v", path, resolved, want)
+		}
+		if _, err := os.Stat(resolved); err != nil {
+			t.Errorf("%v: %v", path, err)
+		}
+	}
+
+	writeFiles(t, app, map[string]string{"vendor/charts/bar.scoop": "var bar = 3;\n"})
+	if _, _, err := load(t, app).Resolve("charts/bar.scoop"); err == nil || !strings.Contains(err.Error(), "run 'scoop mod vendor'") {
+		t.Errorf("want an edited vendored copy to ask for vendor, got %v", err)
+	}
+}
+
+func TestExtractStripsTheTopDirectory(t *testing.T) {
+	tests := []struct {
+		entries []string
+		want    []string
+	}{
+		{[]string{"lib-1.0/a.scoop", "lib-1.0/b/c.scoop"}, []string{"a.scoop", "b/c.scoop"}},
+		{[]string{"a.scoop", "b/c.scoop"}, []string{"a.scoop", "b/c.scoop"}},
+		{[]string{"one/a.scoop", "two/b.scoop"}, []string{"one/a.scoop", "two/b.scoop"}},
+		{[]string{"./lib/a.scoop", "lib/b.scoop"}, []string{"a.scoop", "b.scoop"}},
+	}
+	for _, test := range tests {
+		entries := map[string][]byte{}
+		for _, name := range test.entries {
+			entries[name] = []byte(name)
+		}
+		target := t.TempDir()
+		if err := extract("archive", entries, target); err != nil {
+			t.Errorf("%v: %v", test.entries, err)
+			continue
+		}
+		for _, name := range test.want {
+			if _, err := os.Stat(filepath.Join(target, filepath.FromSlash(name))); err != nil {
+				t.Errorf("%v: want %v to be extracted, %v", test.entries, name, err)
+			}
+		}
+	}
+}
+
+func TestExtractRejectsPathsOutsideTheArchive(t *testing.T) {
+	for _, name := range []string{"../evil.scoop", "lib/../../evil.scoop", "/etc/evil.scoop", ".."} {
+		dir := t.TempDir()
+		target := filepath.Join(dir, "vendor", "lib")
+		err := extract("archive", map[string][]byte{name: []byte("evil"), "lib/ok.scoop": []byte("ok")}, target)
+		if err == nil || !strings.Contains(err.Error(), "outside of the archive") {
+			t.Errorf("%v: want it rejected, got %v", name, err)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "vendor", "evil.scoop")); err == nil {
+			t.Errorf("%v: a file was written outside of the target", name)
+		}
+	}
+
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{"app/scoop.mod": "package app\nrequire evil evil.zip\n"})
+	writeZip(t, filepath.Join(root, "app", "evil.zip"), map[string]string{"../../escaped.scoop": "evil"})
+	if _, err := load(t, filepath.Join(root, "app")).Vendor(); err == nil || !strings.Contains(err.Error(), "outside of the archive") {
+		t.Errorf("want vendor to reject a zip slip, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "escaped.scoop")); err == nil {
+		t.Error("the zip slip wrote a file outside of vendor")
+	}
+}
+
+func writeTarGz(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	compressed := gzip.NewWriter(file)
+	archive := tar.NewWriter(compressed)
+	for name, content := range files {
+		if err := archive.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		archive.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := compressed.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	archive := zip.NewWriter(file)
+	for name, content := range files {
+		entry, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry.Write([]byte(content))
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,306 @@
+package manifest
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Lock is the content of scoop.lock, dependency name -> checksum
+type Lock map[string]string
+
+// ReadLock reads the scoop.lock of the package directory, a missing one is empty
+func ReadLock(dir string) (Lock, error) {
+	lock := Lock{}
+	file, err := os.Open(filepath.Join(dir, LockFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return lock, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for number := 1; scanner.Scan(); number++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, &ManifestError{Path: filepath.Join(dir, LockFileName), Line: number, Message: "expect 'name checksum'"}
+		}
+		lock[fields[0]] = fields[1]
+	}
+	return lock, scanner.Err()
+}
+
+// WriteLock replaces the scoop.lock of the package, one sorted line per dependency
+func (m *Manifest) WriteLock(lock Lock) error {
+	names := make([]string, 0, len(lock))
+	for name := range lock {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var content strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&content, "%v %v\n", name, lock[name])
+	}
+	return os.WriteFile(filepath.Join(m.Dir, LockFileName), []byte(content.String()), 0644)
+}
+
+// Checksum hashes every file under dir with its path relative to dir, so the same tree
+// always gets the same checksum wherever it is
+func Checksum(dir string) (string, error) {
+	files := []string{}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	summary := sha256.New()
+	for _, path := range files {
+		relative, _ := filepath.Rel(dir, path)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(summary, "%x  %v\n", sha256.Sum256(data), filepath.ToSlash(relative))
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
+
+// Tidy checks every dependency can be found and writes their checksums to scoop.lock,
+// dropping the dependencies no longer required
+func (m *Manifest) Tidy() (Lock, error) {
+	lock := Lock{}
+	for _, dependency := range m.Dependencies {
+		checksum, err := m.checksum(dependency)
+		if err != nil {
+			return nil, err
+		}
+		lock[dependency.Name] = checksum
+	}
+	return lock, m.WriteLock(lock)
+}
+
+// Vendor copies every dependency to vendor/<name>, unpacking archives, and writes the
+// checksums of the copies to scoop.lock. The vendor directory is rebuilt from scratch
+func (m *Manifest) Vendor() (Lock, error) {
+	vendor := filepath.Join(m.Dir, VendorDir)
+	if err := os.RemoveAll(vendor); err != nil {
+		return nil, err
+	}
+
+	lock := Lock{}
+	for _, dependency := range m.Dependencies {
+		target := m.VendorPath(dependency)
+		if err := m.unpack(dependency, target); err != nil {
+			return nil, err
+		}
+		checksum, err := Checksum(target)
+		if err != nil {
+			return nil, err
+		}
+		lock[dependency.Name] = checksum
+	}
+	return lock, m.WriteLock(lock)
+}
+
+// checksum hashes the dependency as it would be vendored, archives are unpacked first
+func (m *Manifest) checksum(dependency *Dependency) (string, error) {
+	if !dependency.IsArchive() {
+		if err := m.checkSource(dependency); err != nil {
+			return "", err
+		}
+		return Checksum(m.SourcePath(dependency))
+	}
+
+	temporary, err := os.MkdirTemp("", "scoop-"+dependency.Name+"-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(temporary)
+	if err := m.unpack(dependency, temporary); err != nil {
+		return "", err
+	}
+	return Checksum(temporary)
+}
+
+func (m *Manifest) checkSource(dependency *Dependency) error {
+	source := m.SourcePath(dependency)
+	info, err := os.Stat(source)
+	if err != nil {
+		return &ManifestError{Path: filepath.Join(m.Dir, FileName), Line: dependency.Line, Message: "can't find '" + dependency.Name + "' at " + source}
+	}
+	if !info.IsDir() && !dependency.IsArchive() {
+		return &ManifestError{Path: filepath.Join(m.Dir, FileName), Line: dependency.Line, Message: "'" + dependency.Name + "' must be a directory or a .tar.gz, .tgz or .zip archive"}
+	}
+	return nil
+}
+
+// unpack writes the files of the dependency under target
+func (m *Manifest) unpack(dependency *Dependency, target string) error {
+	if err := m.checkSource(dependency); err != nil {
+		return err
+	}
+	source := m.SourcePath(dependency)
+	switch {
+	case strings.HasSuffix(source, ".zip"):
+		return unzip(source, target)
+	case dependency.IsArchive():
+		return untar(source, target)
+	}
+	return copyTree(source, target)
+}
+
+func copyTree(source string, target string) error {
+	return filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, _ := filepath.Rel(source, path)
+		if entry.IsDir() {
+			return os.MkdirAll(filepath.Join(target, relative), 0755)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		return writeFile(filepath.Join(target, relative), file)
+	})
+}
+
+func untar(source string, target string) error {
+	file, err := os.Open(source)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	compressed, err := gzip.NewReader(file)
+	if err != nil {
+		return fmt.Errorf("%v: %v", source, err)
+	}
+	defer compressed.Close()
+
+	entries := map[string][]byte{}
+	archive := tar.NewReader(compressed)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %v", source, err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(archive)
+		if err != nil {
+			return fmt.Errorf("%v: %v", source, err)
+		}
+		entries[header.Name] = data
+	}
+	return extract(source, entries, target)
+}
+
+func unzip(source string, target string) error {
+	archive, err := zip.OpenReader(source)
+	if err != nil {
+		return fmt.Errorf("%v: %v", source, err)
+	}
+	defer archive.Close()
+
+	entries := map[string][]byte{}
+	for _, file := range archive.File {
+		if !file.Mode().IsRegular() {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return fmt.Errorf("%v: %v", source, err)
+		}
+		data, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return fmt.Errorf("%v: %v", source, err)
+		}
+		entries[file.Name] = data
+	}
+	return extract(source, entries, target)
+}
+
+// extract writes the files of an archive under target. Archives usually wrap everything
+// in one directory named after the release, it is left out so imports don't depend on it
+func extract(source string, entries map[string][]byte, target string) error {
+	names := map[string][]byte{}
+	for name, data := range entries {
+		clean := filepath.ToSlash(filepath.Clean(filepath.FromSlash(name)))
+		if filepath.IsAbs(name) || clean == ".." || strings.HasPrefix(clean, "../") {
+			return fmt.Errorf("%v: '%v' is outside of the archive", source, name)
+		}
+		names[clean] = data
+	}
+
+	prefix := ""
+	for name := range names {
+		top, _, nested := strings.Cut(name, "/")
+		if !nested || (prefix != "" && prefix != top) {
+			prefix = ""
+			break
+		}
+		prefix = top
+	}
+
+	for name, data := range names {
+		if prefix != "" {
+			name = strings.TrimPrefix(name, prefix+"/")
+		}
+		if err := writeFile(filepath.Join(target, filepath.FromSlash(name)), bytes.NewReader(data)); err != nil {
+			return err
+		}
+	}
+	return os.MkdirAll(target, 0755)
+}
+
+func writeFile(path string, content io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, content); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"scoop/components"
+	"scoop/semantics"
+	"testing"
+)
+
+// writeAst saves the syntax tree of source the way "scoop ast --format=json" does
+func writeAst(t *testing.T, source string) string {
+	t.Helper()
+	statements, err := components.InitParser(components.InitScanner(source).ScanTokens()).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := semantics.InitJSONEncoder().Encode(statements)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "program.json")
+	if err := os.WriteFile(path, encoded, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// a decoded program runs like a script, check reports the same static errors for both
+func TestRunAndCheckAstFile(t *testing.T) {
+	reset := func() {
+		interpreter = *semantics.InitInterpreter()
+		HadError, HadRuntimeError = false, false
+	}
+	t.Cleanup(reset)
+
+	tests := []struct {
+		source string
+		run    int
+		check  int
+	}{
+		{"var a = 1;\na = 2;\n", 0, 0},
+		{"const a = 1;\nvar a = 2;\n", 70, 65},
+		{"const a = 1;\na = 2;\n", 70, 65},
+		{"{ var a = 1; var a = 2; }\n", 0, 65},
+	}
+	for _, test := range tests {
+		path := writeAst(t, test.source)
+		reset()
+		if code := (&Scoop{}).runAstFile(path); code != test.run {
+			t.Errorf("%q: want run to exit with %v, got %v", test.source, test.run, code)
+		}
+		reset()
+		if code := (&Scoop{}).checkCommand([]string{path}); code != test.check {
+			t.Errorf("%q: want check to exit with %v, got %v", test.source, test.check, code)
+		}
+	}
+}
+
+// only check resolves, run, -e and the REPL leave the mistakes it finds to the interpreter
+func TestRunDoesNotResolve(t *testing.T) {
+	t.Cleanup(func() {
+		interpreter = *semantics.InitInterpreter()
+		HadError, HadRuntimeError = false, false
+	})
+
+	for _, test := range []struct {
+		source string
+		want   int
+	}{
+		{"{ var a = 1; var a = 2; print a; }", 0},
+		{"{ var a = a; }", 70},
+		{"const a = 1; a = 2;", 70},
+	} {
+		interpreter = *semantics.InitInterpreter()
+		HadError, HadRuntimeError = false, false
+		if code := (&Scoop{}).evalCommand(test.source, nil); code != test.want {
+			t.Errorf("%q: want exit status %v, got %v", test.source, test.want, code)
+		}
+	}
+}
@@ -1,16 +1,21 @@
 package semantics
 
+import "sort"
+
 type Environment struct {
 	enclosing *Environment
 	values    map[string]interface{}
+	// names bound by "const" in this scope
+	constants map[string]bool
 }
 
 func InitEnvironment(enclosing *Environment) *Environment {
-	return &Environment{enclosing: enclosing, values: make(map[string]interface{})}
+	return &Environment{enclosing: enclosing, values: make(map[string]interface{}), constants: make(map[string]bool)}
 }
 
 func (e *Environment) define(name string, value interface{}) {
 	e.values[name] = value
+	delete(e.constants, name)
 	// fmt.Printf("This is the environment %v", e.values)
 }
 
@@ -23,11 +28,24 @@ func (e *Environment) get(name Token) interface{} {
 	if e.enclosing != nil {
 		return e.enclosing.get(name)
 	}
-	panic(&RuntimeError{token: name, message: "Undefined variable '" + name.Lexeme + "'."})
+	panic(&RuntimeError{Token: name, Message: "Undefined variable '" + name.Lexeme + "'."})
+}
+
+func (e *Environment) defineConstant(name string, value interface{}) {
+	e.values[name] = value
+	e.constants[name] = true
+}
+
+// IsConstant reports whether the name is bound by "const" directly in this scope
+func (e *Environment) IsConstant(name string) bool {
+	return e.constants[name]
 }
 
 func (e *Environment) assign(name Token, value interface{}) {
 	if _, ok := e.values[name.Lexeme]; ok {
+		if e.constants[name.Lexeme] {
+			panic(&RuntimeError{Token: name, Message: "Can't assign to constant '" + name.Lexeme + "'."})
+		}
 		e.values[name.Lexeme] = value
 		return
 	}
@@ -37,5 +55,26 @@ func (e *Environment) assign(name Token, value interface{}) {
 		return
 	}
 
-	panic(&RuntimeError{token: name, message: "Undefined variable '" + name.Lexeme + "'."})
+	panic(&RuntimeError{Token: name, Message: "Undefined variable '" + name.Lexeme + "'."})
+}
+
+// Enclosing returns the scope this one is nested in, nil for the globals
+func (e *Environment) Enclosing() *Environment {
+	return e.enclosing
+}
+
+// Names returns the variables defined directly in this scope in alphabetical order
+func (e *Environment) Names() []string {
+	names := []string{}
+	for name := range e.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Lookup returns the value of a variable defined directly in this scope
+func (e *Environment) Lookup(name string) (interface{}, bool) {
+	value, found := e.values[name]
+	return value, found
 }
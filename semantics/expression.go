@@ -7,6 +7,11 @@ type Visitor interface {
 	visitUnaryExpression(u *Unary) interface{}
 	visitVariableDeclarationExpression(v *Variable) interface{}
 	visitAssignmentExpression(a *Assignment) interface{}
+	visitLogicalExpression(l *Logical) interface{}
+	visitConditionalExpression(c *Conditional) interface{}
+	visitUpdateExpression(u *Update) interface{}
+	visitGetExpression(g *Get) interface{}
+	visitCallExpression(c *Call) interface{}
 }
 
 type Expression interface {
@@ -93,6 +98,8 @@ func InitVariable(token Token) *Variable {
 type Assignment struct {
 	Name  Token
 	Value Expression
+	// Operator is "+=", "-=", "*=" or "/=" for a compound assignment, unset for plain "="
+	Operator Token
 }
 
 func (a *Assignment) Accept(visitor Visitor) interface{} {
@@ -107,6 +114,132 @@ func InitAssignment(name Token, value Expression) *Assignment {
 	}
 }
 
+func InitCompoundAssignment(name Token, operator Token, value Expression) *Assignment {
+	return &Assignment{
+		Name:     name,
+		Value:    value,
+		Operator: operator,
+	}
+}
+
+// IsCompound reports whether the assignment combines the old value with the new one, e.g. a += 1
+func (a *Assignment) IsCompound() bool {
+	_, compound := compoundOperators[a.Operator.TokenType]
+	return compound
+}
+
+// compoundOperators maps a compound assignment to the binary operator it applies
+var compoundOperators = map[TokenType]TokenType{
+	PLUS_EQUAL:  PLUS,
+	MINUS_EQUAL: MINUS,
+	STAR_EQUAL:  STAR,
+	SLASH_EQUAL: SLASH,
+}
+
+// Update is ++ or -- on a variable, before (Prefix) or after it
+type Update struct {
+	Name     Token
+	Operator Token
+	Prefix   bool
+}
+
+func (u *Update) Accept(visitor Visitor) interface{} {
+	return visitor.visitUpdateExpression(u)
+}
+
+func InitUpdate(name Token, operator Token, prefix bool) *Update {
+	return &Update{
+		Name:     name,
+		Operator: operator,
+		Prefix:   prefix,
+	}
+}
+
+// Logical is a binary operator that may not evaluate its right operand, e.g. "??"
+type Logical struct {
+	left     Expression
+	operator Token
+	right    Expression
+}
+
+func (l *Logical) Accept(visitor Visitor) interface{} {
+	return visitor.visitLogicalExpression(l)
+}
+
+func InitLogical(left Expression, operator Token, right Expression) *Logical {
+	return &Logical{
+		left:     left,
+		operator: operator,
+		right:    right,
+	}
+}
+
+// Conditional is the ternary "condition ? then : else"
+type Conditional struct {
+	condition  Expression
+	question   Token
+	thenBranch Expression
+	elseBranch Expression
+}
+
+func (c *Conditional) Accept(visitor Visitor) interface{} {
+	return visitor.visitConditionalExpression(c)
+}
+
+func InitConditional(condition Expression, question Token, thenBranch Expression, elseBranch Expression) *Conditional {
+	return &Conditional{
+		condition:  condition,
+		question:   question,
+		thenBranch: thenBranch,
+		elseBranch: elseBranch,
+	}
+}
+
+// Get reads a name out of a module, "str.name". With the "?." operator a nil object
+// gives nil instead of an error
+type Get struct {
+	Object   Expression
+	Operator Token
+	Name     Token
+}
+
+func (g *Get) Accept(visitor Visitor) interface{} {
+	return visitor.visitGetExpression(g)
+}
+
+func InitGet(object Expression, operator Token, name Token) *Get {
+	return &Get{
+		Object:   object,
+		Operator: operator,
+		Name:     name,
+	}
+}
+
+// IsOptional reports whether this is a "?." safe navigation
+func (g *Get) IsOptional() bool {
+	return g.Operator.TokenType == QUESTION_DOT
+}
+
+// Call invokes a function, "math.sqrt(2)". Paren is the closing parenthesis, runtime
+// errors of the call are reported on its line
+type Call struct {
+	Callee    Expression
+	Paren     Token
+	Arguments []Expression
+}
+
+func (c *Call) Accept(visitor Visitor) interface{} {
+	return visitor.visitCallExpression(c)
+}
+
+func InitCall(callee Expression, paren Token, arguments []Expression) *Call {
+	return &Call{
+		Callee:    callee,
+		Paren:     paren,
+		Arguments: arguments,
+	}
+}
+
 // A major difference between Expression and statements is that a statement does not determine
 // the value of  and entity in programming languages but an expression does more so an expression
 // in a value of some sort
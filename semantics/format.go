@@ -0,0 +1,243 @@
+package semantics
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// builtins are the functions every script and module can call without importing them
+var builtins = map[string]*Native{
+	"format": {Name: "format", Arity: 1, Variadic: true, Function: format},
+}
+
+// newGlobals is an empty global scope holding the builtins, scripts may redeclare them
+func newGlobals() *Environment {
+	globals := InitEnvironment(nil)
+	for name, native := range builtins {
+		globals.define(name, native)
+	}
+	return globals
+}
+
+// format fills the placeholders of a template with the arguments after it,
+// format("{} + {} = {}", 1, 2, 3). A placeholder is {index:spec}, both parts are optional:
+//
+//	index      which argument, counting from 0, the next one without it
+//	spec       [[fill]align][+][0][width][.precision][type]
+//	align      < left, > right, ^ centre (numbers go right and the rest left by default)
+//	+          a sign in front of positive numbers too
+//	0          pad numbers with zeros after the sign
+//	width      the least number of characters written
+//	precision  digits after the point for numbers, the most characters for the rest
+//	type       b o x X whole numbers in base 2, 8 or 16, e scientific, f fixed point
+//
+// {{ and }} write a single brace. Every argument must be used
+func format(call *NativeCall) interface{} {
+	template := []rune(call.String(0))
+	arguments := call.Arguments[1:]
+	used := make([]bool, len(arguments))
+	next := 0
+
+	var builder strings.Builder
+	for i := 0; i < len(template); i++ {
+		character := template[i]
+		if character == '}' {
+			if i+1 < len(template) && template[i+1] == '}' {
+				i++
+				builder.WriteRune('}')
+				continue
+			}
+			call.Error("format found a '}' without its '{', write '}}' for a brace.")
+		}
+		if character != '{' {
+			builder.WriteRune(character)
+			continue
+		}
+		if i+1 < len(template) && template[i+1] == '{' {
+			i++
+			builder.WriteRune('{')
+			continue
+		}
+
+		end := i + 1
+		for end < len(template) && template[end] != '}' {
+			end++
+		}
+		if end == len(template) {
+			call.Error("format found a '{' without its '}', write '{{' for a brace.")
+		}
+		placeholder := string(template[i+1 : end])
+		i = end
+
+		written, spec, _ := strings.Cut(placeholder, ":")
+		index := next
+		if written != "" {
+			number, err := strconv.Atoi(written)
+			if err != nil || number < 0 {
+				call.Error("format placeholder {" + placeholder + "} must start with the index of an argument.")
+			}
+			index = number
+		} else {
+			next++
+		}
+		if index >= len(arguments) {
+			call.Error(fmt.Sprintf("format has no argument %v for placeholder {%v}, it was given %v.", index, placeholder, len(arguments)))
+		}
+		used[index] = true
+		builder.WriteString(formatValue(call, arguments[index], placeholder, spec))
+		call.CheckStringLength(builder.Len())
+	}
+
+	for index, isUsed := range used {
+		if !isUsed {
+			call.Error(fmt.Sprintf("format was given argument %v but the template has no placeholder for it.", index))
+		}
+	}
+	return builder.String()
+}
+
+// maxSpecNumber bounds the width and precision of a placeholder, a larger one is an
+// invalid spec rather than a huge allocation
+const maxSpecNumber = 1000
+
+// formatSpec is the part of a placeholder after ':'
+type formatSpec struct {
+	fill      rune
+	align     rune
+	plus      bool
+	zero      bool
+	width     int
+	precision int
+	kind      rune
+}
+
+func parseFormatSpec(spec string) (formatSpec, bool) {
+	parsed := formatSpec{fill: ' ', precision: -1}
+	characters := []rune(spec)
+	isAlign := func(character rune) bool {
+		return character == '<' || character == '>' || character == '^'
+	}
+
+	i := 0
+	if len(characters) > 1 && isAlign(characters[1]) {
+		parsed.fill, parsed.align = characters[0], characters[1]
+		i = 2
+	} else if len(characters) > 0 && isAlign(characters[0]) {
+		parsed.align = characters[0]
+		i = 1
+	}
+	if i < len(characters) && characters[i] == '+' {
+		parsed.plus = true
+		i++
+	}
+	if i < len(characters) && characters[i] == '0' {
+		parsed.zero = true
+		i++
+	}
+	start := i
+	for i < len(characters) && characters[i] >= '0' && characters[i] <= '9' {
+		i++
+	}
+	if i > start {
+		width, err := strconv.Atoi(string(characters[start:i]))
+		if err != nil || width > maxSpecNumber {
+			return parsed, false
+		}
+		parsed.width = width
+	}
+	if i < len(characters) && characters[i] == '.' {
+		i++
+		start = i
+		for i < len(characters) && characters[i] >= '0' && characters[i] <= '9' {
+			i++
+		}
+		if i == start {
+			return parsed, false
+		}
+		precision, err := strconv.Atoi(string(characters[start:i]))
+		if err != nil || precision > maxSpecNumber {
+			return parsed, false
+		}
+		parsed.precision = precision
+	}
+	if i < len(characters) && strings.ContainsRune("boxXef", characters[i]) {
+		parsed.kind = characters[i]
+		i++
+	}
+	return parsed, i == len(characters)
+}
+
+// formatValue writes one argument the way its placeholder asks
+func formatValue(call *NativeCall, value interface{}, placeholder string, text string) string {
+	spec, ok := parseFormatSpec(text)
+	if !ok {
+		call.Error(fmt.Sprintf("format placeholder {%v} has an invalid spec, expected [[fill]align][+][0][width][.precision][type] with a width and precision up to %v.", placeholder, maxSpecNumber))
+	}
+	number, isNumber := value.(float64)
+	if spec.kind != 0 && !isNumber {
+		call.Error(fmt.Sprintf("format placeholder {%v} needs a number, found %v.", placeholder, TypeName(value)))
+	}
+
+	var written string
+	switch {
+	case spec.kind == 'b' || spec.kind == 'o' || spec.kind == 'x' || spec.kind == 'X':
+		if number != math.Trunc(number) || math.Abs(number) >= 1<<63 {
+			call.Error(fmt.Sprintf("format placeholder {%v} needs a whole number, found %v.", placeholder, call.Stringify(number)))
+		}
+		base := map[rune]int{'b': 2, 'o': 8, 'x': 16, 'X': 16}[spec.kind]
+		written = strconv.FormatInt(int64(number), base)
+		if spec.kind == 'X' {
+			written = strings.ToUpper(written)
+		}
+	case spec.kind == 'e':
+		written = strconv.FormatFloat(number, 'e', spec.precision, 64)
+	case spec.kind == 'f' || (isNumber && spec.precision >= 0):
+		precision := spec.precision
+		if precision < 0 {
+			precision = 6
+		}
+		written = strconv.FormatFloat(number, 'f', precision, 64)
+	default:
+		written = call.Stringify(value)
+		if !isNumber && spec.precision >= 0 && utf8.RuneCountInString(written) > spec.precision {
+			written = string([]rune(written)[:spec.precision])
+		}
+	}
+
+	sign := ""
+	if isNumber {
+		if strings.HasPrefix(written, "-") {
+			sign, written = "-", written[1:]
+		} else if spec.plus && !math.IsNaN(number) {
+			sign = "+"
+		}
+	}
+
+	missing := spec.width - utf8.RuneCountInString(sign+written)
+	if missing <= 0 {
+		return sign + written
+	}
+	call.CheckStringLength(spec.width)
+	if spec.zero && isNumber && spec.align == 0 {
+		return sign + strings.Repeat("0", missing) + written
+	}
+
+	align := spec.align
+	if align == 0 {
+		align = '<'
+		if isNumber {
+			align = '>'
+		}
+	}
+	fill := string(spec.fill)
+	switch align {
+	case '>':
+		return strings.Repeat(fill, missing) + sign + written
+	case '^':
+		return strings.Repeat(fill, missing/2) + sign + written + strings.Repeat(fill, missing-missing/2)
+	}
+	return sign + written + strings.Repeat(fill, missing)
+}
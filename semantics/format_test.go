@@ -0,0 +1,74 @@
+package semantics_test
+
+import (
+	"errors"
+	"scoop/components"
+	"scoop/semantics"
+	"strings"
+	"testing"
+)
+
+// run parses and interprets source, returning what it printed and the runtime error
+func run(t *testing.T, source string) (string, error) {
+	t.Helper()
+	statements, err := components.InitParser(components.InitScanner(source).ScanTokens()).Parse()
+	if err != nil {
+		t.Fatalf("parse %q: %v", source, err)
+	}
+	var out strings.Builder
+	interpreter := semantics.InitInterpreter()
+	interpreter.SetOutput(&out)
+	interpreter.SetRawPrint(true)
+	err = interpreter.Interprete(statements)
+	return out.String(), err
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`print format("{} + {} = {}", 1, 2, 3);`, "1 + 2 = 3"},
+		{`print format("[{:>5}] [{:<5}] [{:*^6}]", "ab", "ab", "ab");`, "[   ab] [ab   ] [**ab**]"},
+		{`print format("{:08.2f} {:+} {:.2e}", -3.14159, 7, 12345.678);`, "-0003.14 +7 1.23e+04"},
+		{`print format("{:b} {:o} {:x} {:X}", 10, 64, 255, 255);`, "1010 100 ff FF"},
+		{`print format("{1} {0} {{}}", "a", "b");`, "b a {}"},
+		{`print format("{:.3}|{:>4}|", "héllo", "é");`, "hél|   é|"},
+	}
+	for _, test := range tests {
+		out, err := run(t, test.source)
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.source, err)
+			continue
+		}
+		if got := strings.TrimSuffix(out, "\n"); got != test.want {
+			t.Errorf("%v\n got %q\nwant %q", test.source, got, test.want)
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`print format("{:9999999999999999999999}", 1);`, "invalid spec"},
+		{`print format("{:.9999999999999999999}", 1);`, "invalid spec"},
+		{`print format("{:1001}", 1);`, "invalid spec"},
+		{`print format("{}");`, "no argument 0"},
+		{`print format("{}", 1, 2);`, "no placeholder"},
+		{`print format("{:x}", 1.5);`, "needs a whole number"},
+		{`print format("{", 1);`, "without its '}'"},
+	}
+	for _, test := range tests {
+		_, err := run(t, test.source)
+		var runtimeErr *semantics.RuntimeError
+		if !errors.As(err, &runtimeErr) {
+			t.Errorf("%v: want a RuntimeError, got %v", test.source, err)
+			continue
+		}
+		if !strings.Contains(runtimeErr.Message, test.want) {
+			t.Errorf("%v: message %q does not mention %q", test.source, runtimeErr.Message, test.want)
+		}
+	}
+}
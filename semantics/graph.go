@@ -0,0 +1,260 @@
+package semantics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// GraphPrinter renders a program as a Graphviz DOT digraph.
+// Every node of the syntax tree becomes a box with edges to its children, labelled with
+// the role the child plays (left, right, condition...). With scopes turned on, the global
+// scope and every Block are drawn as clusters, variables point at the declaration they
+// resolve to and a Var that shadows an outer one gets a red "shadows" edge
+type GraphPrinter struct {
+	builder  *strings.Builder
+	nodes    int
+	clusters int
+	depth    int
+	scopes   bool
+	// declarations in each open scope, innermost last, name -> node id of the Var
+	declarations []map[string]string
+}
+
+func InitGraphPrinter(scopes bool) *GraphPrinter {
+	return &GraphPrinter{scopes: scopes}
+}
+
+func (g *GraphPrinter) Print(statements []Statement) string {
+	g.builder = &strings.Builder{}
+	g.nodes = 0
+	g.clusters = 0
+	g.depth = 1
+	g.declarations = nil
+
+	g.builder.WriteString("digraph scoop {\n")
+	g.line("node [shape=box, fontname=\"monospace\"];")
+
+	g.beginScope("global scope")
+	root := g.node("Program")
+	for _, statement := range statements {
+		g.edge(root, g.statement(statement), "")
+	}
+	g.endScope()
+
+	g.builder.WriteString("}\n")
+	return g.builder.String()
+}
+
+func (g *GraphPrinter) statement(statement Statement) string {
+	return statement.Accept(g).(string)
+}
+
+func (g *GraphPrinter) expression(expression Expression) string {
+	return expression.Accept(g).(string)
+}
+
+func (g *GraphPrinter) visitExpressionStatement(statement *ExpressionStatement) interface{} {
+	id := g.node("Expression")
+	g.edge(id, g.expression(statement.Expr), "")
+	return id
+}
+
+func (g *GraphPrinter) visitPrintStatement(statement *Print) interface{} {
+	id := g.node("Print")
+	for _, value := range statement.Values {
+		g.edge(id, g.expression(value), "")
+	}
+	return id
+}
+
+func (g *GraphPrinter) visitVariableDeclarationStatement(statement *Var) interface{} {
+	label := "Var " + statement.Name.Lexeme
+	if statement.Constant {
+		label = "Const " + statement.Name.Lexeme
+	}
+	if statement.Exported {
+		label = "Export " + label
+	}
+	id := g.node(label)
+	// the initialiser runs before the name is defined so it still sees the outer variable
+	if statement.Initialiser != nil {
+		g.edge(id, g.expression(statement.Initialiser), "init")
+	}
+	g.declare(id, statement.Name.Lexeme)
+	return id
+}
+
+func (g *GraphPrinter) visitBlockStatement(block *Block) interface{} {
+	g.beginScope("block")
+	id := g.node("Block")
+	for _, statement := range block.Statements {
+		g.edge(id, g.statement(statement), "")
+	}
+	g.endScope()
+	return id
+}
+
+func (g *GraphPrinter) visitIFStatement(conditional *If) interface{} {
+	id := g.node("If")
+	g.edge(id, g.expression(conditional.Condition), "condition")
+	g.edge(id, g.statement(conditional.ThenBranch), "then")
+	if conditional.ElseBranch != nil {
+		g.edge(id, g.statement(conditional.ElseBranch), "else")
+	}
+	return id
+}
+
+func (g *GraphPrinter) visitImportStatement(statement *Import) interface{} {
+	id := g.node(fmt.Sprintf("Import %q as %v", statement.Path.Literal, statement.Name.Lexeme))
+	g.declare(id, statement.Name.Lexeme)
+	return id
+}
+
+func (g *GraphPrinter) visitGetExpression(get *Get) interface{} {
+	id := g.node("Get " + get.Operator.Lexeme + get.Name.Lexeme)
+	g.edge(id, g.expression(get.Object), "object")
+	return id
+}
+
+func (g *GraphPrinter) visitCallExpression(call *Call) interface{} {
+	id := g.node("Call")
+	g.edge(id, g.expression(call.Callee), "callee")
+	for index, argument := range call.Arguments {
+		g.edge(id, g.expression(argument), fmt.Sprintf("argument %v", index+1))
+	}
+	return id
+}
+
+func (g *GraphPrinter) visitBinaryExpression(binary *Binary) interface{} {
+	id := g.node("Binary " + binary.operator.Lexeme)
+	g.edge(id, g.expression(binary.left), "left")
+	g.edge(id, g.expression(binary.right), "right")
+	return id
+}
+
+func (g *GraphPrinter) visitGroupingExpression(grouping *Grouping) interface{} {
+	id := g.node("Grouping")
+	g.edge(id, g.expression(grouping.expression), "")
+	return id
+}
+
+func (g *GraphPrinter) visitLiteralExpression(literal *Literal) interface{} {
+	return g.node("Literal " + InitAbstractSyntaxTreePrinter().Print(literal))
+}
+
+func (g *GraphPrinter) visitUnaryExpression(unary *Unary) interface{} {
+	id := g.node("Unary " + unary.operator.Lexeme)
+	g.edge(id, g.expression(unary.right), "right")
+	return id
+}
+
+func (g *GraphPrinter) visitVariableDeclarationExpression(variable *Variable) interface{} {
+	id := g.node("Variable " + variable.Name.Lexeme)
+	g.resolve(id, variable.Name.Lexeme)
+	return id
+}
+
+func (g *GraphPrinter) visitLogicalExpression(logical *Logical) interface{} {
+	id := g.node("Logical " + logical.operator.Lexeme)
+	g.edge(id, g.expression(logical.left), "left")
+	g.edge(id, g.expression(logical.right), "right")
+	return id
+}
+
+func (g *GraphPrinter) visitConditionalExpression(conditional *Conditional) interface{} {
+	id := g.node("Conditional ?:")
+	g.edge(id, g.expression(conditional.condition), "condition")
+	g.edge(id, g.expression(conditional.thenBranch), "then")
+	g.edge(id, g.expression(conditional.elseBranch), "else")
+	return id
+}
+
+func (g *GraphPrinter) visitUpdateExpression(update *Update) interface{} {
+	label := "Update " + update.Name.Lexeme + update.Operator.Lexeme
+	if update.Prefix {
+		label = "Update " + update.Operator.Lexeme + update.Name.Lexeme
+	}
+	id := g.node(label)
+	g.resolve(id, update.Name.Lexeme)
+	return id
+}
+
+func (g *GraphPrinter) visitAssignmentExpression(assignment *Assignment) interface{} {
+	label := "Assignment " + assignment.Name.Lexeme
+	if assignment.IsCompound() {
+		label = "Assignment " + assignment.Name.Lexeme + " " + assignment.Operator.Lexeme
+	}
+	id := g.node(label)
+	g.edge(id, g.expression(assignment.Value), "value")
+	g.resolve(id, assignment.Name.Lexeme)
+	return id
+}
+
+// node declares a new box and returns its id
+func (g *GraphPrinter) node(label string) string {
+	id := fmt.Sprintf("n%d", g.nodes)
+	g.nodes++
+	g.line(fmt.Sprintf("%v [label=%v];", id, strconv.Quote(label)))
+	return id
+}
+
+func (g *GraphPrinter) edge(from string, to string, label string) {
+	if label == "" {
+		g.line(fmt.Sprintf("%v -> %v;", from, to))
+		return
+	}
+	g.line(fmt.Sprintf("%v -> %v [label=%v];", from, to, strconv.Quote(label)))
+}
+
+func (g *GraphPrinter) line(text string) {
+	g.builder.WriteString(strings.Repeat("  ", g.depth))
+	g.builder.WriteString(text)
+	g.builder.WriteString("\n")
+}
+
+func (g *GraphPrinter) beginScope(label string) {
+	g.declarations = append(g.declarations, map[string]string{})
+	if !g.scopes {
+		return
+	}
+	g.line(fmt.Sprintf("subgraph cluster_%d {", g.clusters))
+	g.clusters++
+	g.depth++
+	g.line(fmt.Sprintf("label=%v; style=dashed; color=gray;", strconv.Quote(label)))
+}
+
+func (g *GraphPrinter) endScope() {
+	g.declarations = g.declarations[:len(g.declarations)-1]
+	if !g.scopes {
+		return
+	}
+	g.depth--
+	g.line("}")
+}
+
+// declare records a Var in the innermost scope and marks any outer declaration it hides
+func (g *GraphPrinter) declare(id string, name string) {
+	if g.scopes {
+		for i := len(g.declarations) - 2; i >= 0; i-- {
+			if shadowed, found := g.declarations[i][name]; found {
+				g.line(fmt.Sprintf("%v -> %v [label=\"shadows\", style=dashed, color=red, fontcolor=red, constraint=false];", id, shadowed))
+				break
+			}
+		}
+	}
+	g.declarations[len(g.declarations)-1][name] = id
+}
+
+// resolve links a use of a variable to the Var it refers to at this point in the program
+func (g *GraphPrinter) resolve(id string, name string) {
+	if !g.scopes {
+		return
+	}
+	for i := len(g.declarations) - 1; i >= 0; i-- {
+		if declaration, found := g.declarations[i][name]; found {
+			g.line(fmt.Sprintf("%v -> %v [style=dotted, color=blue, constraint=false];", id, declaration))
+			return
+		}
+	}
+}
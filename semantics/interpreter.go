@@ -1,32 +1,110 @@
 package semantics
 
 import (
+	"context"
 	"fmt"
+	"io"
 	// "log"
+	"math"
+	"os"
 	"strings"
 )
 
 // var env *Environment
 
 type Interpreter struct {
-	env *Environment
+	env     *Environment
+	globals *Environment
+	out     io.Writer
+	// styleValue lets the host decorate printed values (e.g. colour them by type)
+	styleValue func(value interface{}, text string) string
+	stepHook   StepHook
+	// printPrefix starts every line print writes
+	printPrefix string
+	// how many statements enclose the one being executed
+	depth int
+
+	limits Limits
+	ctx    context.Context
+	steps  int
+	// how many blocks enclose the one being executed
+	blocks int
+	// the line of the statement being executed, for LimitErrors
+	line int
+
+	loader ModuleLoader
+	// modules already run, by resolved path
+	modules map[string]*Module
+	// the file being executed and the chain of imports that led to it, from the main script
+	path      string
+	importing []string
+}
+
+// StepHook is called before every statement is executed, debuggers use it to pause the
+// program. depth is the number of statements enclosing this one (0 at the top level) and
+// env the scope it runs in. Returning an error stops the program, Interprete returns it
+type StepHook func(statement Statement, depth int, env *Environment) error
+
+// stepStopped carries the error of a StepHook up to Interprete
+type stepStopped struct {
+	err error
 }
 
 type RuntimeError struct {
-	token   Token
-	message string
+	Token   Token
+	Message string
 }
 
 func InitInterpreter() *Interpreter {
-	return &Interpreter{env: InitEnvironment(nil)}
+	globals := newGlobals()
+	return &Interpreter{
+		env:         globals,
+		globals:     globals,
+		out:         os.Stdout,
+		ctx:         context.Background(),
+		printPrefix: ">> ",
+		styleValue: func(value interface{}, text string) string {
+			return text
+		},
+	}
 }
 
 func (e *RuntimeError) Error() string {
-	return fmt.Sprintf("Parse error at %v: %s", e.token, e.message)
+	return fmt.Sprintf("Runtime error at %v: %s", e.Token, e.Message)
 }
 
 func (p *Interpreter) error(token Token, message string) error {
-	return &RuntimeError{token: token, message: message}
+	return &RuntimeError{Token: token, Message: message}
+}
+
+// DefineGlobal makes a value provided by the host visible to scripts as a global variable
+func (p *Interpreter) DefineGlobal(name string, value interface{}) {
+	p.globals.define(name, value)
+}
+
+func (p *Interpreter) SetStepHook(hook StepHook) {
+	p.stepHook = hook
+}
+
+// Stringify returns the text print would show for the value
+func (p *Interpreter) Stringify(value interface{}) string {
+	return p.stringify(value)
+}
+
+func (p *Interpreter) SetOutput(out io.Writer) {
+	p.out = out
+}
+
+// SetRawPrint drops the ">> " print puts in front of every line, for output read by other programs
+func (p *Interpreter) SetRawPrint(raw bool) {
+	p.printPrefix = ">> "
+	if raw {
+		p.printPrefix = ""
+	}
+}
+
+func (p *Interpreter) SetValueStyler(styleValue func(value interface{}, text string) string) {
+	p.styleValue = styleValue
 }
 
 // for single expression
@@ -35,19 +113,89 @@ func (p *Interpreter) error(token Token, message string) error {
 // 	fmt.Printf(p.stringify(value) + "\n")
 // }
 
-func (p *Interpreter) Interprete(expr []Statement) {
+// Interprete executes the statements in order and stops at the first RuntimeError, which is returned
+func (p *Interpreter) Interprete(expr []Statement) (err error) {
+	return p.InterpreteContext(context.Background(), expr)
+}
+
+// InterpreteContext is Interprete stopping with a LimitError once ctx is done or a Limit is exceeded.
+// The step budget and the timeout apply to each call
+func (p *Interpreter) InterpreteContext(ctx context.Context, expr []Statement) (err error) {
+	if p.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.limits.Timeout)
+		defer cancel()
+	}
+	p.ctx = ctx
+	p.steps = 0
+	p.blocks = 0
+
+	defer func() {
+		p.ctx = context.Background()
+		if recovered := recover(); recovered != nil {
+			if runtimeErr, ok := recovered.(*RuntimeError); ok {
+				err = runtimeErr
+				return
+			}
+			if limitErr, ok := recovered.(*LimitError); ok {
+				err = limitErr
+				return
+			}
+			if stopped, ok := recovered.(*stepStopped); ok {
+				err = stopped.err
+				return
+			}
+			panic(recovered)
+		}
+	}()
+	p.depth = 0
 	// log.Println("\ninside interpreter now...")
 	for _, statement := range expr {
 		p.execute(statement)
 	}
+	return nil
+}
+
+// Evaluate computes the expression in env rather than the current scope, a debugger uses
+// it while the program is paused inside a StepHook
+func (p *Interpreter) Evaluate(expression Expression, env *Environment) (value interface{}, err error) {
+	previous := p.env
+	p.env = env
+	defer func() {
+		p.env = previous
+		if recovered := recover(); recovered != nil {
+			if runtimeErr, ok := recovered.(*RuntimeError); ok {
+				value, err = nil, runtimeErr
+				return
+			}
+			if limitErr, ok := recovered.(*LimitError); ok {
+				value, err = nil, limitErr
+				return
+			}
+			panic(recovered)
+		}
+	}()
+	return p.evaluate(expression), nil
 }
 
 func (p *Interpreter) execute(statement Statement) {
+	p.line = statement.Start().Line
+	p.step()
+	if p.stepHook != nil {
+		if err := p.stepHook(statement, p.depth, p.env); err != nil {
+			panic(&stepStopped{err: err})
+		}
+	}
+
+	p.depth++
+	defer func() {
+		p.depth--
+	}()
 	statement.Accept(p)
 }
 
 func (p *Interpreter) visitIFStatement(statement *If) interface{} {
-	if p.isTruthy(statement.Condition) {
+	if p.isTruthy(p.evaluate(statement.Condition)) {
 		p.execute(statement.ThenBranch)
 	} else if statement.ElseBranch != nil {
 		p.execute(statement.ElseBranch)
@@ -60,6 +208,18 @@ func (p *Interpreter) stringify(objectA interface{}) string {
 		return "nil"
 	}
 
+	if list, ok := objectA.(*List); ok {
+		return p.stringifyList(list)
+	}
+
+	if module, ok := objectA.(*Module); ok {
+		return "<module " + module.Name + ">"
+	}
+
+	if native, ok := objectA.(*Native); ok {
+		return "<native fn " + native.Name + ">"
+	}
+
 	if _, ok := objectA.(float64); ok {
 		text := fmt.Sprintf("%v", objectA)
 		if strings.HasSuffix(text, ".0") {
@@ -78,8 +238,12 @@ func (p *Interpreter) visitExpressionStatement(exprStatement *ExpressionStatemen
 }
 
 func (p *Interpreter) visitPrintStatement(printStatement *Print) interface{} {
-	value := p.evaluate(printStatement.Expr)
-	fmt.Print(">> " + p.stringify(value) + "\n")
+	parts := []string{}
+	for _, expression := range printStatement.Values {
+		value := p.evaluate(expression)
+		parts = append(parts, p.styleValue(value, p.stringify(value)))
+	}
+	fmt.Fprint(p.out, p.printPrefix+strings.Join(parts, " ")+"\n")
 	return nil
 }
 
@@ -91,32 +255,93 @@ func (p *Interpreter) visitBlockStatement(blockStatement *Block) interface{} {
 func (p *Interpreter) executeBlockStatement(statements []Statement, env *Environment) {
 	previous := p.env
 	p.env = env
+	p.blocks++
+	// restore the outer scope even when a RuntimeError unwinds through the block
+	defer func() {
+		p.env = previous
+		p.blocks--
+	}()
+	p.checkDepth()
 	for _, statement := range statements {
 		p.execute(statement)
 	}
-
-	p.env = previous
 }
 
 func (p *Interpreter) visitVariableDeclarationStatement(varStatement *Var) interface{} {
+	if varStatement.Exported && p.env != p.globals {
+		panic(p.error(varStatement.Name, "Only top level declarations can be exported."))
+	}
 
 	var value interface{}
 	if varStatement.Initialiser != nil {
 		value = p.evaluate(varStatement.Initialiser)
 	}
 
-	p.env.define(varStatement.Name.Lexeme, value)
+	// globals may be redeclared, but not over a constant
+	if p.env.IsConstant(varStatement.Name.Lexeme) {
+		panic(p.error(varStatement.Name, "Can't redeclare constant '"+varStatement.Name.Lexeme+"'."))
+	}
+	if varStatement.Constant {
+		p.env.defineConstant(varStatement.Name.Lexeme, value)
+	} else {
+		p.env.define(varStatement.Name.Lexeme, value)
+	}
 	// fmt.Printf("This is the environment during declaration statement %v", p.env.values)
 
 	return nil
 }
 
 func (p *Interpreter) visitAssignmentExpression(assignment *Assignment) interface{} {
+	if assignment.IsCompound() {
+		current := p.env.get(assignment.Name)
+		operator := assignment.Operator
+		operator.TokenType = compoundOperators[operator.TokenType]
+		value := p.binary(operator, current, p.evaluate(assignment.Value))
+		p.env.assign(assignment.Name, value)
+		return value
+	}
+
 	value := p.evaluate(assignment.Value)
 	p.env.assign(assignment.Name, value)
 	return value
 }
 
+// visitUpdateExpression returns the new value for ++x and the old one for x++
+func (p *Interpreter) visitUpdateExpression(update *Update) interface{} {
+	current := p.env.get(update.Name)
+	p.checkNumberOperand(update.Operator, current)
+
+	value := current.(float64) + 1
+	if update.Operator.TokenType == MINUS_MINUS {
+		value = current.(float64) - 1
+	}
+	p.env.assign(update.Name, value)
+	if update.Prefix {
+		return value
+	}
+	return current
+}
+
+// visitLogicalExpression only evaluates the right operand when the left one doesn't decide the result
+func (p *Interpreter) visitLogicalExpression(logical *Logical) interface{} {
+	left := p.evaluate(logical.left)
+
+	switch logical.operator.TokenType {
+	case QUESTION_QUESTION:
+		if left != nil {
+			return left
+		}
+	}
+	return p.evaluate(logical.right)
+}
+
+func (p *Interpreter) visitConditionalExpression(conditional *Conditional) interface{} {
+	if p.isTruthy(p.evaluate(conditional.condition)) {
+		return p.evaluate(conditional.thenBranch)
+	}
+	return p.evaluate(conditional.elseBranch)
+}
+
 func (p *Interpreter) visitVariableDeclarationExpression(varExpression *Variable) interface{} {
 	// fmt.Printf("This is the environment during declaration expression %v", p.env.values)
 	return p.env.get(varExpression.Name)
@@ -133,17 +358,42 @@ func (p *Interpreter) visitGroupingExpression(groupExpr *Grouping) interface{} {
 func (p *Interpreter) visitBinaryExpression(binExpr *Binary) interface{} {
 	left := p.evaluate(binExpr.left)
 	right := p.evaluate(binExpr.right)
+	return p.binary(binExpr.operator, left, right)
+}
 
-	switch binExpr.operator.TokenType {
+// binary applies a binary operator, compound assignments share it with visitBinaryExpression
+func (p *Interpreter) binary(operator Token, left interface{}, right interface{}) interface{} {
+	switch operator.TokenType {
 	case MINUS:
-		p.checkNumberOperands(binExpr.operator, left, right)
+		p.checkNumberOperands(operator, left, right)
 		return float64(left.(float64)) - float64(right.(float64))
 	case SLASH:
-		p.checkNumberOperands(binExpr.operator, left, right)
+		p.checkNumberOperands(operator, left, right)
 		return float64(left.(float64)) / float64(right.(float64))
 	case STAR:
-		p.checkNumberOperands(binExpr.operator, left, right)
+		p.checkNumberOperands(operator, left, right)
 		return float64(left.(float64)) * float64(right.(float64))
+	case STAR_STAR:
+		p.checkNumberOperands(operator, left, right)
+		return math.Pow(left.(float64), right.(float64))
+	case PERCENT:
+		p.checkNumberOperands(operator, left, right)
+		return math.Mod(left.(float64), right.(float64))
+	case AMPERSAND:
+		return float64(p.integer(operator, left) & p.integer(operator, right))
+	case PIPE:
+		return float64(p.integer(operator, left) | p.integer(operator, right))
+	case CARET:
+		return float64(p.integer(operator, left) ^ p.integer(operator, right))
+	case LESS_LESS, GREATER_GREATER:
+		value, count := p.integer(operator, left), p.integer(operator, right)
+		if count < 0 {
+			panic(p.error(operator, "Shift count can't be negative."))
+		}
+		if operator.TokenType == LESS_LESS {
+			return float64(value << count)
+		}
+		return float64(value >> count)
 	case PLUS:
 		switch left := left.(type) {
 		case float64:
@@ -152,20 +402,22 @@ func (p *Interpreter) visitBinaryExpression(binExpr *Binary) interface{} {
 			}
 		case string:
 			if right, ok := right.(string); ok {
+				p.checkStringLength(operator, len(left)+len(right))
 				return left + right
 			}
 		}
+		panic(p.error(operator, "Operands must be two numbers or two strings."))
 	case GREATER:
-		p.checkNumberOperands(binExpr.operator, left, right)
+		p.checkNumberOperands(operator, left, right)
 		return float64(left.(float64)) > float64(right.(float64))
 	case GREATER_EQUAL:
-		p.checkNumberOperands(binExpr.operator, left, right)
+		p.checkNumberOperands(operator, left, right)
 		return float64(left.(float64)) >= float64(right.(float64))
 	case LESS:
-		p.checkNumberOperands(binExpr.operator, left, right)
+		p.checkNumberOperands(operator, left, right)
 		return float64(left.(float64)) < float64(right.(float64))
 	case LESS_EQUAL:
-		p.checkNumberOperands(binExpr.operator, left, right)
+		p.checkNumberOperands(operator, left, right)
 		return float64(left.(float64)) <= float64(right.(float64))
 	case BANG_EQUAL:
 		return !p.isEqual(left, right)
@@ -180,9 +432,12 @@ func (p *Interpreter) visitUnaryExpression(unaryExpr *Unary) interface{} {
 
 	switch unaryExpr.operator.TokenType {
 	case MINUS:
+		p.checkNumberOperand(unaryExpr.operator, right)
 		return -right.(float64)
 	case BANG:
 		return !p.isTruthy(right)
+	case TILDE:
+		return float64(^p.integer(unaryExpr.operator, right))
 	}
 
 	return nil
@@ -215,6 +470,15 @@ func (p *Interpreter) checkNumberOperands(operator Token, operandA interface{},
 	}
 }
 
+// integer converts the operand of a bitwise operator, only whole numbers are allowed
+func (p *Interpreter) integer(operator Token, operand interface{}) int64 {
+	value, ok := operand.(float64)
+	if !ok || value != math.Trunc(value) || value < math.MinInt64 || value >= math.MaxInt64 {
+		panic(p.error(operator, "Operands of '"+operator.Lexeme+"' must be integers."))
+	}
+	return int64(value)
+}
+
 func (p *Interpreter) isTruthy(object interface{}) bool {
 	if object == nil {
 		return false
@@ -226,5 +490,6 @@ func (p *Interpreter) isTruthy(object interface{}) bool {
 }
 
 func (p *Interpreter) evaluate(expr Expression) interface{} {
+	p.step()
 	return expr.Accept(p)
 }
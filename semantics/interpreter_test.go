@@ -0,0 +1,123 @@
+package semantics_test
+
+import (
+	"errors"
+	"scoop/semantics"
+	"strings"
+	"testing"
+)
+
+// the condition of an if used to be tested as the unevaluated syntax node, which is
+// always truthy, so every if ran its then branch and the condition never ran
+func TestIfEvaluatesItsCondition(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`if (true) print "then"; else print "else";`, "then"},
+		{`if (false) print "then"; else print "else";`, "else"},
+		{`if (nil) print "then"; else print "else";`, "else"},
+		{`if (0) print "then"; else print "else";`, "then"},
+		{`if (1 > 2) print "then";`, ""},
+		{`var n = 0; if (n++ == 0) print n;`, "1"},
+		{`var a = 1; if (a == 2) print "two"; else if (a == 1) print "one"; else print "other";`, "one"},
+	}
+	for _, test := range tests {
+		out, err := run(t, test.source)
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.source, err)
+			continue
+		}
+		if got := strings.TrimSuffix(out, "\n"); got != test.want {
+			t.Errorf("%v\n got %q\nwant %q", test.source, got, test.want)
+		}
+	}
+}
+
+func TestPlusNeedsTwoNumbersOrTwoStrings(t *testing.T) {
+	for _, source := range []string{
+		`print 1 + "s";`,
+		`print "s" + 1;`,
+		`print nil + nil;`,
+		`print true + 1;`,
+		`var n = 1; n += "s"; print n;`,
+		`var s = "s"; s += 1; print s;`,
+	} {
+		out, err := run(t, source)
+		var runtimeErr *semantics.RuntimeError
+		if !errors.As(err, &runtimeErr) || runtimeErr.Message != "Operands must be two numbers or two strings." {
+			t.Errorf("%v: want a RuntimeError on the operands, got %v", source, err)
+		}
+		if out != "" {
+			t.Errorf("%v: printed %q before the error", source, out)
+		}
+	}
+
+	out, err := run(t, `var n = 1; n += 2; var s = "a"; s += "b"; print n, s;`)
+	if err != nil || out != "3 ab\n" {
+		t.Errorf("got %q %v", out, err)
+	}
+}
+
+// the resolver only runs for scoop check, the interpreter stops these on its own
+func TestTopLevelOnlyStatements(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`{ export var a = 1; }`, "Only top level declarations can be exported."},
+		{`{ import "math" as math; }`, "Modules can only be imported at the top level of a script."},
+	}
+	for _, test := range tests {
+		_, err := run(t, test.source)
+		var runtimeErr *semantics.RuntimeError
+		if !errors.As(err, &runtimeErr) || runtimeErr.Message != test.want {
+			t.Errorf("%v: want %q, got %v", test.source, test.want, err)
+		}
+	}
+
+	out, err := run(t, `export var a = 1; import "math" as math; print a, math.pi > 3;`)
+	if err != nil || out != "1 true\n" {
+		t.Errorf("got %q %v", out, err)
+	}
+}
+
+func TestConditionalCoalesceAndSafeNavigation(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		// ?: picks a branch by truthiness and only evaluates that branch
+		{`print 1 > 0 ? "a" : "b", nil ? 1 : 2, 0 ? "zero" : "no";`, "a 2 zero"},
+		{`var c = 0; print true ? 1 : c++, c;`, "1 0"},
+		{`var c = 0; print false ? c++ : 2, c;`, "2 0"},
+		{`print false ? 1 : true ? 2 : 3;`, "2"},
+		{`print true ? false ? 1 : 2 : 3;`, "2"},
+		// ?? only replaces nil and only evaluates its right side then
+		{`print nil ?? "default", false ?? "default", 0 ?? "default";`, "default false 0"},
+		{`var c = 0; print 1 ?? c++, c;`, "1 0"},
+		{`var c = 0; print nil ?? c++, c;`, "0 1"},
+		{`print nil ?? nil ?? 3;`, "3"},
+		// ?. gives nil instead of an error when the value is nil
+		{`var n; print n?.length;`, "nil"},
+		{`var n; print n?.length ?? 0;`, "0"},
+		{`print "ab"?.length();`, "2"},
+		{`import "math" as m; print m?.pi > 3;`, "true"},
+	}
+	for _, test := range tests {
+		out, err := run(t, test.source)
+		if err != nil {
+			t.Errorf("%v: unexpected error %v", test.source, err)
+			continue
+		}
+		if got := strings.TrimSuffix(out, "\n"); got != test.want {
+			t.Errorf("%v\n got %q\nwant %q", test.source, got, test.want)
+		}
+	}
+
+	_, err := run(t, `var n = 1; print n?.length;`)
+	var runtimeErr *semantics.RuntimeError
+	if !errors.As(err, &runtimeErr) {
+		t.Errorf("want ?. on a number to be a runtime error, got %v", err)
+	}
+}
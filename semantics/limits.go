@@ -0,0 +1,82 @@
+package semantics
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Limits bound the resources a script may use, a zero field means unlimited.
+// Hosts running untrusted scripts set them with Interpreter.SetLimits
+type Limits struct {
+	// MaxSteps counts every statement executed and every expression evaluated
+	MaxSteps int
+	// MaxDepth is how deeply blocks may be nested while running
+	MaxDepth int
+	// MaxStringLength is the longest string, in bytes, that + may build
+	MaxStringLength int
+	// Timeout is the wall-clock time a call to Interprete may take
+	Timeout time.Duration
+}
+
+// Which limit a LimitError is about
+const (
+	LimitSteps        = "steps"
+	LimitDepth        = "depth"
+	LimitStringLength = "string length"
+	LimitTimeout      = "timeout"
+	LimitCancelled    = "cancelled"
+)
+
+// LimitError aborts a script that went over one of its Limits or whose context was
+// cancelled. It is returned by Interprete like a RuntimeError but scripts can never
+// cause it on purpose, so hosts can tell "the script failed" from "the script was stopped"
+type LimitError struct {
+	Limit   string
+	Line    int
+	Message string
+	// Cause is the context's error for LimitTimeout and LimitCancelled
+	Cause error
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("Execution stopped at line %v: %s", e.Line, e.Message)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Cause
+}
+
+func (p *Interpreter) SetLimits(limits Limits) {
+	p.limits = limits
+}
+
+// step counts one statement or expression against MaxSteps and notices a cancelled context
+func (p *Interpreter) step() {
+	p.steps++
+	if p.limits.MaxSteps > 0 && p.steps > p.limits.MaxSteps {
+		panic(&LimitError{Limit: LimitSteps, Line: p.line, Message: fmt.Sprintf("The script ran more than %v steps.", p.limits.MaxSteps)})
+	}
+
+	select {
+	case <-p.ctx.Done():
+		err := p.ctx.Err()
+		if err == context.DeadlineExceeded {
+			panic(&LimitError{Limit: LimitTimeout, Line: p.line, Message: "The script ran out of time.", Cause: err})
+		}
+		panic(&LimitError{Limit: LimitCancelled, Line: p.line, Message: "The script was cancelled.", Cause: err})
+	default:
+	}
+}
+
+func (p *Interpreter) checkDepth() {
+	if p.limits.MaxDepth > 0 && p.blocks > p.limits.MaxDepth {
+		panic(&LimitError{Limit: LimitDepth, Line: p.line, Message: fmt.Sprintf("Blocks are nested more than %v deep.", p.limits.MaxDepth)})
+	}
+}
+
+func (p *Interpreter) checkStringLength(operator Token, length int) {
+	if p.limits.MaxStringLength > 0 && length > p.limits.MaxStringLength {
+		panic(&LimitError{Limit: LimitStringLength, Line: operator.Line, Message: fmt.Sprintf("The string would be longer than %v bytes.", p.limits.MaxStringLength)})
+	}
+}
@@ -0,0 +1,255 @@
+package semantics
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Lint rules, the names are what "// scoop:ignore <rule>" comments refer to
+const (
+	RuleShadow            = "shadow"
+	RuleUnused            = "unused"
+	RuleSelfAssign        = "self-assign"
+	RuleConstantCondition = "constant-condition"
+	RuleUnreachable       = "unreachable"
+)
+
+// Warning is a legal but suspicious piece of code found by the Linter
+type Warning struct {
+	Token   Token
+	Rule    string
+	Message string
+}
+
+func (w *Warning) String() string {
+	return fmt.Sprintf("[line %v] Warning (%v) : %v", w.Token.Line, w.Rule, w.Message)
+}
+
+type lintDeclaration struct {
+	name Token
+	read bool
+}
+
+// Linter walks the program with the same scoping rules as the interpreter and
+// collects Warnings for code that runs but is probably not what was meant
+type Linter struct {
+	scopes   []map[string]*lintDeclaration
+	warnings []*Warning
+}
+
+func InitLinter() *Linter {
+	return &Linter{}
+}
+
+// Lint returns the warnings for the program ordered by line
+func (l *Linter) Lint(statements []Statement) []*Warning {
+	l.scopes = nil
+	l.warnings = nil
+
+	l.beginScope()
+	l.lintStatements(statements)
+	l.endScope()
+
+	sort.Slice(l.warnings, func(i, j int) bool {
+		a, b := l.warnings[i], l.warnings[j]
+		if a.Token.Line != b.Token.Line {
+			return a.Token.Line < b.Token.Line
+		}
+		if a.Rule != b.Rule {
+			return a.Rule < b.Rule
+		}
+		return a.Message < b.Message
+	})
+	return l.warnings
+}
+
+func (l *Linter) warn(token Token, rule string, message string) {
+	l.warnings = append(l.warnings, &Warning{Token: token, Rule: rule, Message: message})
+}
+
+func (l *Linter) lintStatements(statements []Statement) {
+	for _, statement := range statements {
+		statement.Accept(l)
+	}
+}
+
+func (l *Linter) lintExpression(expression Expression) {
+	expression.Accept(l)
+}
+
+func (l *Linter) beginScope() {
+	l.scopes = append(l.scopes, map[string]*lintDeclaration{})
+}
+
+// endScope reports the variables of the closing scope that were never read
+func (l *Linter) endScope() {
+	scope := l.scopes[len(l.scopes)-1]
+	for _, declaration := range scope {
+		if !declaration.read {
+			l.warn(declaration.name, RuleUnused, "Variable '"+declaration.name.Lexeme+"' is declared but never read.")
+		}
+	}
+	l.scopes = l.scopes[:len(l.scopes)-1]
+}
+
+func (l *Linter) declare(name Token) {
+	for i := len(l.scopes) - 2; i >= 0; i-- {
+		if outer, found := l.scopes[i][name.Lexeme]; found {
+			l.warn(name, RuleShadow, fmt.Sprintf("Variable '%v' shadows the one declared on line %v.", name.Lexeme, outer.name.Line))
+			break
+		}
+	}
+
+	scope := l.scopes[len(l.scopes)-1]
+	if previous, found := scope[name.Lexeme]; found && !previous.read {
+		l.warn(previous.name, RuleUnused, "Variable '"+previous.name.Lexeme+"' is declared but never read.")
+	}
+	scope[name.Lexeme] = &lintDeclaration{name: name}
+}
+
+func (l *Linter) lookup(name Token) *lintDeclaration {
+	for i := len(l.scopes) - 1; i >= 0; i-- {
+		if declaration, found := l.scopes[i][name.Lexeme]; found {
+			return declaration
+		}
+	}
+	return nil
+}
+
+// constant returns the value of a condition made only of a literal, e.g. (true)
+func (l *Linter) constant(expression Expression) (interface{}, bool) {
+	switch expression := expression.(type) {
+	case *Literal:
+		return expression.value, true
+	case *Grouping:
+		return l.constant(expression.expression)
+	}
+	return nil, false
+}
+
+func (l *Linter) visitExpressionStatement(statement *ExpressionStatement) interface{} {
+	l.lintExpression(statement.Expr)
+	return nil
+}
+
+func (l *Linter) visitPrintStatement(statement *Print) interface{} {
+	for _, value := range statement.Values {
+		l.lintExpression(value)
+	}
+	return nil
+}
+
+func (l *Linter) visitVariableDeclarationStatement(statement *Var) interface{} {
+	if statement.Initialiser != nil {
+		l.lintExpression(statement.Initialiser)
+	}
+	l.declare(statement.Name)
+	// importers read exported names, they are never unused
+	if statement.Exported {
+		l.lookup(statement.Name).read = true
+	}
+	return nil
+}
+
+func (l *Linter) visitBlockStatement(block *Block) interface{} {
+	l.beginScope()
+	l.lintStatements(block.Statements)
+	l.endScope()
+	return nil
+}
+
+func (l *Linter) visitIFStatement(conditional *If) interface{} {
+	l.lintExpression(conditional.Condition)
+
+	if value, ok := l.constant(conditional.Condition); ok {
+		truthy := value != nil && value != false
+		l.warn(conditional.Keyword, RuleConstantCondition, fmt.Sprintf("Condition is always %v.", truthy))
+		if !truthy {
+			l.warn(conditional.Keyword, RuleUnreachable, "The then branch can never run.")
+		} else if conditional.ElseBranch != nil {
+			l.warn(conditional.Keyword, RuleUnreachable, "The else branch can never run.")
+		}
+	}
+
+	conditional.ThenBranch.Accept(l)
+	if conditional.ElseBranch != nil {
+		conditional.ElseBranch.Accept(l)
+	}
+	return nil
+}
+
+func (l *Linter) visitImportStatement(statement *Import) interface{} {
+	l.declare(statement.Name)
+	return nil
+}
+
+func (l *Linter) visitGetExpression(get *Get) interface{} {
+	l.lintExpression(get.Object)
+	return nil
+}
+
+func (l *Linter) visitCallExpression(call *Call) interface{} {
+	l.lintExpression(call.Callee)
+	for _, argument := range call.Arguments {
+		l.lintExpression(argument)
+	}
+	return nil
+}
+
+func (l *Linter) visitBinaryExpression(binary *Binary) interface{} {
+	l.lintExpression(binary.left)
+	l.lintExpression(binary.right)
+	return nil
+}
+
+func (l *Linter) visitGroupingExpression(grouping *Grouping) interface{} {
+	l.lintExpression(grouping.expression)
+	return nil
+}
+
+func (l *Linter) visitLiteralExpression(literal *Literal) interface{} {
+	return nil
+}
+
+func (l *Linter) visitUnaryExpression(unary *Unary) interface{} {
+	l.lintExpression(unary.right)
+	return nil
+}
+
+func (l *Linter) visitVariableDeclarationExpression(variable *Variable) interface{} {
+	if declaration := l.lookup(variable.Name); declaration != nil {
+		declaration.read = true
+	}
+	return nil
+}
+
+func (l *Linter) visitLogicalExpression(logical *Logical) interface{} {
+	l.lintExpression(logical.left)
+	l.lintExpression(logical.right)
+	return nil
+}
+
+func (l *Linter) visitConditionalExpression(conditional *Conditional) interface{} {
+	l.lintExpression(conditional.condition)
+	if value, ok := l.constant(conditional.condition); ok {
+		l.warn(conditional.question, RuleConstantCondition, fmt.Sprintf("Condition is always %v.", value != nil && value != false))
+	}
+	l.lintExpression(conditional.thenBranch)
+	l.lintExpression(conditional.elseBranch)
+	return nil
+}
+
+// visitUpdateExpression only writes the variable, x++ alone does not make x used
+func (l *Linter) visitUpdateExpression(update *Update) interface{} {
+	return nil
+}
+
+func (l *Linter) visitAssignmentExpression(assignment *Assignment) interface{} {
+	// a self assignment does not count as reading the variable, a += a is fine though
+	if variable, ok := assignment.Value.(*Variable); ok && variable.Name.Lexeme == assignment.Name.Lexeme && !assignment.IsCompound() {
+		l.warn(assignment.Name, RuleSelfAssign, "Variable '"+assignment.Name.Lexeme+"' is assigned to itself.")
+		return nil
+	}
+	l.lintExpression(assignment.Value)
+	return nil
+}
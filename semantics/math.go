@@ -0,0 +1,71 @@
+package semantics
+
+import "math"
+
+// mathMembers is the math module, functions over the numbers of binary expressions
+func mathMembers() map[string]interface{} {
+	members := map[string]interface{}{
+		"pi":  math.Pi,
+		"e":   math.E,
+		"inf": math.Inf(1),
+		"nan": math.NaN(),
+
+		"min": &Native{Name: "math.min", Arity: 1, Variadic: true, Function: func(call *NativeCall) interface{} {
+			result := call.Number(0)
+			for index := range call.Arguments[1:] {
+				result = math.Min(result, call.Number(index+1))
+			}
+			return result
+		}},
+		"max": &Native{Name: "math.max", Arity: 1, Variadic: true, Function: func(call *NativeCall) interface{} {
+			result := call.Number(0)
+			for index := range call.Arguments[1:] {
+				result = math.Max(result, call.Number(index+1))
+			}
+			return result
+		}},
+		"pow": &Native{Name: "math.pow", Arity: 2, Function: func(call *NativeCall) interface{} {
+			return math.Pow(call.Number(0), call.Number(1))
+		}},
+		"atan2": &Native{Name: "math.atan2", Arity: 2, Function: func(call *NativeCall) interface{} {
+			return math.Atan2(call.Number(0), call.Number(1))
+		}},
+		"isNaN": &Native{Name: "math.isNaN", Arity: 1, Function: func(call *NativeCall) interface{} {
+			return math.IsNaN(call.Number(0))
+		}},
+		"isInf": &Native{Name: "math.isInf", Arity: 1, Function: func(call *NativeCall) interface{} {
+			return math.IsInf(call.Number(0), 0)
+		}},
+		"isFinite": &Native{Name: "math.isFinite", Arity: 1, Function: func(call *NativeCall) interface{} {
+			value := call.Number(0)
+			return !math.IsNaN(value) && !math.IsInf(value, 0)
+		}},
+	}
+
+	// the functions of one number, NaN comes out of those given a number outside of their domain
+	unary := map[string]func(float64) float64{
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+		"round": math.Round,
+		"trunc": math.Trunc,
+		"abs":   math.Abs,
+		"sqrt":  math.Sqrt,
+		"log":   math.Log,
+		"log2":  math.Log2,
+		"log10": math.Log10,
+		"exp":   math.Exp,
+		"sin":   math.Sin,
+		"cos":   math.Cos,
+		"tan":   math.Tan,
+		"asin":  math.Asin,
+		"acos":  math.Acos,
+		"atan":  math.Atan,
+	}
+	for name, function := range unary {
+		function := function
+		members[name] = &Native{Name: "math." + name, Arity: 1, Function: func(call *NativeCall) interface{} {
+			return function(call.Number(0))
+		}}
+	}
+	return members
+}
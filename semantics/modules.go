@@ -0,0 +1,151 @@
+package semantics
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Module is the value an import binds its name to, the globals of the imported script
+type Module struct {
+	Name string
+	// Path is where the module was loaded from, as given by the ModuleLoader
+	Path    string
+	Globals *Environment
+	// the top level names of the module, true for the exported ones
+	Members map[string]bool
+}
+
+// Members returns the names a module declares at its top level, mapped to whether
+// they are exported. Only the exported ones can be read through the module
+func Members(statements []Statement) map[string]bool {
+	members := map[string]bool{}
+	for _, statement := range statements {
+		if declaration, ok := statement.(*Var); ok {
+			members[declaration.Name.Lexeme] = members[declaration.Name.Lexeme] || declaration.Exported
+		}
+	}
+	return members
+}
+
+// ModuleLoader finds and compiles the scripts named by import statements, the interpreter
+// can't scan or parse by itself. Resolve turns the path written in the import into the
+// path of a file, from is the path of the importing script ("" for code that is not in a
+// file). Load returns the statements of a resolved path
+type ModuleLoader interface {
+	Resolve(path string, from string) (string, error)
+	Load(path string) ([]Statement, error)
+}
+
+// SetModuleLoader turns on import statements, without a loader they are a runtime error
+func (p *Interpreter) SetModuleLoader(loader ModuleLoader) {
+	p.loader = loader
+}
+
+// SetScriptPath tells the interpreter which file it runs, the imports of the script are
+// resolved against it and it is part of the import cycle check
+func (p *Interpreter) SetScriptPath(path string) {
+	p.path = path
+	p.importing = []string{path}
+}
+
+func (p *Interpreter) visitImportStatement(statement *Import) interface{} {
+	if p.env != p.globals {
+		panic(p.error(statement.Keyword, "Modules can only be imported at the top level of a script."))
+	}
+	p.env.define(statement.Name.Lexeme, p.importModule(statement))
+	return nil
+}
+
+// importModule runs the imported script the first time it is imported and returns the
+// cached module after that
+func (p *Interpreter) importModule(statement *Import) *Module {
+	written, _ := statement.Path.Literal.(string)
+	if module := NativeModule(written); module != nil {
+		module.Name = statement.Name.Lexeme
+		return module
+	}
+	if p.loader == nil {
+		panic(p.error(statement.Path, "Modules can't be imported here."))
+	}
+	path, err := p.loader.Resolve(written, p.path)
+	if err != nil {
+		panic(p.error(statement.Path, err.Error()))
+	}
+	if module, found := p.modules[path]; found {
+		return &Module{Name: statement.Name.Lexeme, Path: module.Path, Globals: module.Globals, Members: module.Members}
+	}
+
+	for index, importing := range p.importing {
+		if importing == path {
+			cycle := append(append([]string{}, p.importing[index:]...), path)
+			panic(p.error(statement.Path, "Import cycle: "+strings.Join(cycle, " -> ")+"."))
+		}
+	}
+
+	statements, err := p.loader.Load(path)
+	if err != nil {
+		panic(p.error(statement.Path, err.Error()))
+	}
+
+	module := &Module{Name: statement.Name.Lexeme, Path: path, Globals: newGlobals(), Members: Members(statements)}
+	p.runModule(statement, module, statements)
+	if p.modules == nil {
+		p.modules = map[string]*Module{}
+	}
+	p.modules[path] = module
+	return module
+}
+
+// runModule executes the statements of a module in its own globals. Errors are reported
+// on the import so they point into the importing script, and the StepHook is off since
+// a debugger only knows the lines of the main script
+func (p *Interpreter) runModule(statement *Import, module *Module, statements []Statement) {
+	previousEnv, previousGlobals, previousPath, previousHook := p.env, p.globals, p.path, p.stepHook
+	p.env, p.globals, p.path, p.stepHook = module.Globals, module.Globals, module.Path, nil
+	p.importing = append(p.importing, module.Path)
+	defer func() {
+		p.env, p.globals, p.path, p.stepHook = previousEnv, previousGlobals, previousPath, previousHook
+		p.importing = p.importing[:len(p.importing)-1]
+
+		if recovered := recover(); recovered != nil {
+			where := fmt.Sprintf("Error in module '%v' at line ", statement.Path.Literal)
+			if runtimeErr, ok := recovered.(*RuntimeError); ok {
+				panic(p.error(statement.Path, where+fmt.Sprintf("%v: %v", runtimeErr.Token.Line, runtimeErr.Message)))
+			}
+			if limitErr, ok := recovered.(*LimitError); ok {
+				limitErr.Message = where + fmt.Sprintf("%v: %v", limitErr.Line, limitErr.Message)
+				limitErr.Line = statement.Path.Line
+			}
+			panic(recovered)
+		}
+	}()
+
+	for _, statement := range statements {
+		p.execute(statement)
+	}
+}
+
+func (p *Interpreter) visitGetExpression(get *Get) interface{} {
+	object := p.evaluate(get.Object)
+	if object == nil && get.IsOptional() {
+		return nil
+	}
+
+	if text, ok := object.(string); ok {
+		return p.stringMember(text, get.Name)
+	}
+	module, ok := object.(*Module)
+	if !ok {
+		panic(p.error(get.Name, "Only modules and strings have members, found "+p.stringify(object)+"."))
+	}
+	// the builtins are in the globals of a module too, but they are not its members
+	exported, declared := module.Members[get.Name.Lexeme]
+	if !declared {
+		panic(p.error(get.Name, "Module '"+module.Name+"' has no member '"+get.Name.Lexeme+"'."))
+	}
+	if !exported {
+		panic(p.error(get.Name, "'"+get.Name.Lexeme+"' is not exported by module '"+module.Name+"'."))
+	}
+	value, _ := module.Globals.Lookup(get.Name.Lexeme)
+	return value
+}
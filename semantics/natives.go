@@ -0,0 +1,153 @@
+package semantics
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Native is a function written in Go. It takes Arity arguments and then up to Optional
+// more, a Variadic native takes Arity arguments or more
+type Native struct {
+	Name     string
+	Arity    int
+	Optional int
+	Variadic bool
+	Function func(call *NativeCall) interface{}
+}
+
+// NativeCall is what a Native receives, the argument accessors check the type of the
+// arguments and stop the script with a RuntimeError on the line of the call
+type NativeCall struct {
+	Arguments   []interface{}
+	native      *Native
+	paren       Token
+	interpreter *Interpreter
+}
+
+// Number returns the argument at index, which must be a number
+func (c *NativeCall) Number(index int) float64 {
+	value, ok := c.Arguments[index].(float64)
+	if !ok {
+		c.mismatch(index, "number")
+	}
+	return value
+}
+
+// String returns the argument at index, which must be a string
+func (c *NativeCall) String(index int) string {
+	value, ok := c.Arguments[index].(string)
+	if !ok {
+		c.mismatch(index, "string")
+	}
+	return value
+}
+
+// Integer returns the argument at index, which must be a whole number
+func (c *NativeCall) Integer(index int) int {
+	value := c.Number(index)
+	if value != math.Trunc(value) || value < math.MinInt32 || value > math.MaxInt32 {
+		c.Error(fmt.Sprintf("%v expects a whole number as argument %v, found %v.", c.native.Name, index+1, c.Stringify(value)))
+	}
+	return int(value)
+}
+
+// List returns the argument at index, which must be a list
+func (c *NativeCall) List(index int) *List {
+	value, ok := c.Arguments[index].(*List)
+	if !ok {
+		c.mismatch(index, "list")
+	}
+	return value
+}
+
+// Has reports whether the optional argument at index was given
+func (c *NativeCall) Has(index int) bool {
+	return index < len(c.Arguments)
+}
+
+// CheckStringLength stops the script when a string the native builds would be over the limit
+func (c *NativeCall) CheckStringLength(length int) {
+	c.interpreter.checkStringLength(c.paren, length)
+}
+
+// Stringify returns the text print would show for the value
+func (c *NativeCall) Stringify(value interface{}) string {
+	return c.interpreter.stringify(value)
+}
+
+func (c *NativeCall) mismatch(index int, expected string) {
+	c.Error(fmt.Sprintf("%v expects a %v as argument %v, found %v.", c.native.Name, expected, index+1, TypeName(c.Arguments[index])))
+}
+
+// Error stops the script with a RuntimeError on the line of the call
+func (c *NativeCall) Error(message string) {
+	panic(c.interpreter.error(c.paren, message))
+}
+
+// TypeName is how errors and debuggers name the type of a runtime value
+func TypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "nil"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case *List:
+		return "list"
+	case *Module:
+		return "module"
+	case *Native:
+		return "function"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func (p *Interpreter) visitCallExpression(call *Call) interface{} {
+	callee := p.evaluate(call.Callee)
+	arguments := []interface{}{}
+	for _, argument := range call.Arguments {
+		arguments = append(arguments, p.evaluate(argument))
+	}
+
+	native, ok := callee.(*Native)
+	if !ok {
+		panic(p.error(call.Paren, "Can only call functions, found "+TypeName(callee)+"."))
+	}
+	if len(arguments) < native.Arity || (!native.Variadic && len(arguments) > native.Arity+native.Optional) {
+		expected := strconv.Itoa(native.Arity) + " argument"
+		switch {
+		case native.Optional > 0:
+			expected = strconv.Itoa(native.Arity) + " to " + strconv.Itoa(native.Arity+native.Optional) + " arguments"
+		case native.Arity != 1:
+			expected += "s"
+		}
+		if native.Variadic {
+			expected = "at least " + expected
+		}
+		panic(p.error(call.Paren, fmt.Sprintf("%v expects %v but got %v.", native.Name, expected, len(arguments))))
+	}
+	return native.Function(&NativeCall{Arguments: arguments, native: native, paren: call.Paren, interpreter: p})
+}
+
+// nativeModules are the modules written in Go, imported by name, import "math" as math;
+var nativeModules = map[string]func() map[string]interface{}{
+	"math": mathMembers,
+}
+
+// NativeModule returns the module written in Go the import path names, nil if there is none
+func NativeModule(name string) *Module {
+	members, found := nativeModules[name]
+	if !found {
+		return nil
+	}
+	module := &Module{Name: name, Path: name, Globals: InitEnvironment(nil), Members: map[string]bool{}}
+	for member, value := range members() {
+		module.Globals.defineConstant(member, value)
+		module.Members[member] = true
+	}
+	return module
+}
@@ -2,10 +2,15 @@ package semantics
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 )
 
+// AbstractSyntaxTreePrinter renders expressions and statements as S-expressions.
+// Expressions are printed on one line, statements holding other statements (blocks, ifs)
+// put each child on its own line indented two spaces deeper than the parent
 type AbstractSyntaxTreePrinter struct {
+	depth int
 }
 
 func InitAbstractSyntaxTreePrinter() *AbstractSyntaxTreePrinter {
@@ -16,12 +21,94 @@ func (a *AbstractSyntaxTreePrinter) Print(expression Expression) string {
 	return expression.Accept(a).(string)
 }
 
+func (a *AbstractSyntaxTreePrinter) PrintStatement(statement Statement) string {
+	return statement.Accept(a).(string)
+}
+
+// PrintProgram renders every top level statement, one per line
+func (a *AbstractSyntaxTreePrinter) PrintProgram(statements []Statement) string {
+	builder := &strings.Builder{}
+	for _, statement := range statements {
+		builder.WriteString(a.PrintStatement(statement))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+func (a *AbstractSyntaxTreePrinter) visitExpressionStatement(statement *ExpressionStatement) interface{} {
+	return a.parenthesize("expr", statement.Expr)
+}
+
+func (a *AbstractSyntaxTreePrinter) visitPrintStatement(statement *Print) interface{} {
+	return a.parenthesize("print", statement.Values...)
+}
+
+func (a *AbstractSyntaxTreePrinter) visitVariableDeclarationStatement(statement *Var) interface{} {
+	keyword := "var"
+	if statement.Constant {
+		keyword = "const"
+	}
+	if statement.Exported {
+		keyword = "export " + keyword
+	}
+	if statement.Initialiser == nil {
+		return "(" + keyword + " " + statement.Name.Lexeme + ")"
+	}
+	return a.parenthesize(keyword+" "+statement.Name.Lexeme, statement.Initialiser)
+}
+
+func (a *AbstractSyntaxTreePrinter) visitBlockStatement(block *Block) interface{} {
+	return a.nest("block", block.Statements...)
+}
+
+func (a *AbstractSyntaxTreePrinter) visitIFStatement(conditional *If) interface{} {
+	head := "if " + a.Print(conditional.Condition)
+	if conditional.ElseBranch == nil {
+		return a.nest(head, conditional.ThenBranch)
+	}
+	return a.nest(head, conditional.ThenBranch, conditional.ElseBranch)
+}
+
+func (a *AbstractSyntaxTreePrinter) visitImportStatement(statement *Import) interface{} {
+	return "(import " + strconv.Quote(fmt.Sprint(statement.Path.Literal)) + " as " + statement.Name.Lexeme + ")"
+}
+
+// visitGetExpression prints str.name as (. str name)
+func (a *AbstractSyntaxTreePrinter) visitGetExpression(get *Get) interface{} {
+	return "(" + get.Operator.Lexeme + " " + a.Print(get.Object) + " " + get.Name.Lexeme + ")"
+}
+
+// visitCallExpression prints f(x, y) as (call f x y)
+func (a *AbstractSyntaxTreePrinter) visitCallExpression(call *Call) interface{} {
+	return a.parenthesize("call "+a.Print(call.Callee), call.Arguments...)
+}
+
 func (a *AbstractSyntaxTreePrinter) visitAssignmentExpression(assgn *Assignment) interface{} {
-	return nil
+	if assgn.IsCompound() {
+		return a.parenthesize(assgn.Operator.Lexeme+" "+assgn.Name.Lexeme, assgn.Value)
+	}
+	return a.parenthesize("= "+assgn.Name.Lexeme, assgn.Value)
+}
+
+// visitUpdateExpression prints ++x as (pre++ x) and x++ as (post++ x)
+func (a *AbstractSyntaxTreePrinter) visitUpdateExpression(update *Update) interface{} {
+	position := "post"
+	if update.Prefix {
+		position = "pre"
+	}
+	return "(" + position + update.Operator.Lexeme + " " + update.Name.Lexeme + ")"
+}
+
+func (a *AbstractSyntaxTreePrinter) visitLogicalExpression(logical *Logical) interface{} {
+	return a.parenthesize(logical.operator.Lexeme, logical.left, logical.right)
+}
+
+func (a *AbstractSyntaxTreePrinter) visitConditionalExpression(conditional *Conditional) interface{} {
+	return a.parenthesize("?:", conditional.condition, conditional.thenBranch, conditional.elseBranch)
 }
 
 func (a *AbstractSyntaxTreePrinter) visitVariableDeclarationExpression(variable *Variable) interface{} {
-	return nil
+	return variable.Name.Lexeme
 }
 
 func (a *AbstractSyntaxTreePrinter) visitBinaryExpression(binaryExpression *Binary) interface{} {
@@ -37,7 +124,12 @@ func (a *AbstractSyntaxTreePrinter) visitLiteralExpression(literalExpression *Li
 		return "nil"
 	}
 
-	return literalExpression.value
+	// quote strings so they can't be mistaken for variable names
+	if text, ok := literalExpression.value.(string); ok {
+		return strconv.Quote(text)
+	}
+
+	return fmt.Sprint(literalExpression.value)
 }
 
 func (a *AbstractSyntaxTreePrinter) visitUnaryExpression(unaryExpression *Unary) interface{} {
@@ -58,3 +150,22 @@ func (a *AbstractSyntaxTreePrinter) parenthesize(lexeme string, expressionArgs .
 
 	return builder.String()
 }
+
+// nest is parenthesize for statements, each child goes on its own indented line
+func (a *AbstractSyntaxTreePrinter) nest(head string, statements ...Statement) string {
+	builder := &strings.Builder{}
+	builder.WriteString("(")
+	builder.WriteString(head)
+
+	a.depth++
+	for _, statement := range statements {
+		builder.WriteString("\n")
+		builder.WriteString(strings.Repeat("  ", a.depth))
+		builder.WriteString(a.PrintStatement(statement))
+	}
+	a.depth--
+
+	builder.WriteString(")")
+
+	return builder.String()
+}
@@ -0,0 +1,158 @@
+package semantics
+
+// Reference is one occurrence of a variable name in the program, either where it is
+// declared or where it is read or assigned, linked to the Var that declares it.
+// Declaration is nil when the name is not declared in the program (e.g. the host's 'args')
+type Reference struct {
+	Token       Token
+	Declaration *Var
+	Declares    bool
+}
+
+// ReferenceIndex resolves every variable name in a program to its declaration
+// following the same scoping rules as the interpreter, editors use it for
+// go to definition, find references and hovers
+type ReferenceIndex struct {
+	scopes     []map[string]*Var
+	references []*Reference
+}
+
+func InitReferenceIndex() *ReferenceIndex {
+	return &ReferenceIndex{}
+}
+
+// Index returns the references in the order they appear in the program
+func (r *ReferenceIndex) Index(statements []Statement) []*Reference {
+	r.scopes = []map[string]*Var{{}}
+	r.references = nil
+	r.indexStatements(statements)
+	return r.references
+}
+
+func (r *ReferenceIndex) indexStatements(statements []Statement) {
+	for _, statement := range statements {
+		if statement != nil {
+			statement.Accept(r)
+		}
+	}
+}
+
+func (r *ReferenceIndex) indexExpression(expression Expression) {
+	if expression != nil {
+		expression.Accept(r)
+	}
+}
+
+func (r *ReferenceIndex) lookup(name Token) *Var {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if declaration, found := r.scopes[i][name.Lexeme]; found {
+			return declaration
+		}
+	}
+	return nil
+}
+
+func (r *ReferenceIndex) use(name Token) {
+	r.references = append(r.references, &Reference{Token: name, Declaration: r.lookup(name)})
+}
+
+func (r *ReferenceIndex) visitExpressionStatement(statement *ExpressionStatement) interface{} {
+	r.indexExpression(statement.Expr)
+	return nil
+}
+
+func (r *ReferenceIndex) visitPrintStatement(statement *Print) interface{} {
+	for _, value := range statement.Values {
+		r.indexExpression(value)
+	}
+	return nil
+}
+
+func (r *ReferenceIndex) visitVariableDeclarationStatement(statement *Var) interface{} {
+	r.indexExpression(statement.Initialiser)
+	r.scopes[len(r.scopes)-1][statement.Name.Lexeme] = statement
+	r.references = append(r.references, &Reference{Token: statement.Name, Declaration: statement, Declares: true})
+	return nil
+}
+
+func (r *ReferenceIndex) visitBlockStatement(block *Block) interface{} {
+	r.scopes = append(r.scopes, map[string]*Var{})
+	r.indexStatements(block.Statements)
+	r.scopes = r.scopes[:len(r.scopes)-1]
+	return nil
+}
+
+func (r *ReferenceIndex) visitIFStatement(conditional *If) interface{} {
+	r.indexExpression(conditional.Condition)
+	r.indexStatements([]Statement{conditional.ThenBranch, conditional.ElseBranch})
+	return nil
+}
+
+// visitImportStatement hides an outer variable of the same name, the module itself is not a Var
+func (r *ReferenceIndex) visitImportStatement(statement *Import) interface{} {
+	r.scopes[len(r.scopes)-1][statement.Name.Lexeme] = nil
+	return nil
+}
+
+// visitGetExpression indexes the object, members live in another file
+func (r *ReferenceIndex) visitGetExpression(get *Get) interface{} {
+	r.indexExpression(get.Object)
+	return nil
+}
+
+func (r *ReferenceIndex) visitCallExpression(call *Call) interface{} {
+	r.indexExpression(call.Callee)
+	for _, argument := range call.Arguments {
+		r.indexExpression(argument)
+	}
+	return nil
+}
+
+func (r *ReferenceIndex) visitBinaryExpression(binary *Binary) interface{} {
+	r.indexExpression(binary.left)
+	r.indexExpression(binary.right)
+	return nil
+}
+
+func (r *ReferenceIndex) visitGroupingExpression(grouping *Grouping) interface{} {
+	r.indexExpression(grouping.expression)
+	return nil
+}
+
+func (r *ReferenceIndex) visitLiteralExpression(literal *Literal) interface{} {
+	return nil
+}
+
+func (r *ReferenceIndex) visitUnaryExpression(unary *Unary) interface{} {
+	r.indexExpression(unary.right)
+	return nil
+}
+
+func (r *ReferenceIndex) visitVariableDeclarationExpression(variable *Variable) interface{} {
+	r.use(variable.Name)
+	return nil
+}
+
+func (r *ReferenceIndex) visitLogicalExpression(logical *Logical) interface{} {
+	r.indexExpression(logical.left)
+	r.indexExpression(logical.right)
+	return nil
+}
+
+func (r *ReferenceIndex) visitConditionalExpression(conditional *Conditional) interface{} {
+	r.indexExpression(conditional.condition)
+	r.indexExpression(conditional.thenBranch)
+	r.indexExpression(conditional.elseBranch)
+	return nil
+}
+
+func (r *ReferenceIndex) visitUpdateExpression(update *Update) interface{} {
+	r.use(update.Name)
+	return nil
+}
+
+func (r *ReferenceIndex) visitAssignmentExpression(assignment *Assignment) interface{} {
+	r.indexExpression(assignment.Value)
+	r.use(assignment.Name)
+	return nil
+}
@@ -0,0 +1,284 @@
+package semantics
+
+import "fmt"
+
+// Resolver is a static pass over a parsed program, run by "scoop check" and the language server.
+// It walks the scopes the same way the interpreter will and reports the
+// mistakes that can be caught without running the program
+type Resolver struct {
+	// one map per open block, name -> whether its initialiser has finished
+	scopes []map[string]bool
+	// names declared with "const", one map per open block next to scopes
+	constants       []map[string]bool
+	globalConstants map[string]bool
+	errors          []error
+
+	// with a loader, the members of imported modules are checked too
+	loader ModuleLoader
+	path   string
+	// the members of the modules imported at the top level, by the name they are bound to
+	modules map[string]map[string]bool
+}
+
+type ResolveError struct {
+	Token   Token
+	Message string
+}
+
+func (e *ResolveError) Error() string {
+	return fmt.Sprintf("Resolve error at %v: %s", e.Token, e.Message)
+}
+
+func InitResolver() *Resolver {
+	return &Resolver{}
+}
+
+// Resolve checks the program and returns every error found, nil when there are none
+func (r *Resolver) Resolve(statements []Statement) []error {
+	r.scopes = nil
+	r.constants = nil
+	r.globalConstants = map[string]bool{}
+	r.modules = map[string]map[string]bool{}
+	r.errors = nil
+	r.resolveStatements(statements)
+	return r.errors
+}
+
+// SetModuleLoader lets the resolver read imported modules to check the names used from them,
+// path is the script being resolved, imports are relative to it
+func (r *Resolver) SetModuleLoader(loader ModuleLoader, path string) {
+	r.loader = loader
+	r.path = path
+}
+
+func (r *Resolver) error(token Token, message string) {
+	r.errors = append(r.errors, &ResolveError{Token: token, Message: message})
+}
+
+func (r *Resolver) resolveStatements(statements []Statement) {
+	for _, statement := range statements {
+		statement.Accept(r)
+	}
+}
+
+func (r *Resolver) resolveExpression(expression Expression) {
+	expression.Accept(r)
+}
+
+func (r *Resolver) beginScope() {
+	r.scopes = append(r.scopes, map[string]bool{})
+	r.constants = append(r.constants, map[string]bool{})
+}
+
+func (r *Resolver) endScope() {
+	r.scopes = r.scopes[:len(r.scopes)-1]
+	r.constants = r.constants[:len(r.constants)-1]
+}
+
+// declare adds the name to the innermost block, globals are left alone as they may be
+// redeclared unless they are constants
+func (r *Resolver) declare(name Token, constant bool) {
+	if len(r.scopes) == 0 {
+		if r.globalConstants[name.Lexeme] {
+			r.error(name, "Can't redeclare constant '"+name.Lexeme+"'.")
+		}
+		r.globalConstants[name.Lexeme] = constant
+		return
+	}
+	scope := r.scopes[len(r.scopes)-1]
+	if _, found := scope[name.Lexeme]; found {
+		r.error(name, "Already a variable with this name in this scope.")
+	}
+	scope[name.Lexeme] = false
+	r.constants[len(r.constants)-1][name.Lexeme] = constant
+}
+
+func (r *Resolver) define(name Token) {
+	if len(r.scopes) == 0 {
+		return
+	}
+	r.scopes[len(r.scopes)-1][name.Lexeme] = true
+}
+
+func (r *Resolver) visitExpressionStatement(statement *ExpressionStatement) interface{} {
+	r.resolveExpression(statement.Expr)
+	return nil
+}
+
+func (r *Resolver) visitPrintStatement(statement *Print) interface{} {
+	for _, value := range statement.Values {
+		r.resolveExpression(value)
+	}
+	return nil
+}
+
+func (r *Resolver) visitVariableDeclarationStatement(statement *Var) interface{} {
+	if statement.Exported && len(r.scopes) > 0 {
+		r.error(statement.Name, "Only top level declarations can be exported.")
+	}
+	if len(r.scopes) == 0 {
+		delete(r.modules, statement.Name.Lexeme)
+	}
+	r.declare(statement.Name, statement.Constant)
+	if statement.Initialiser != nil {
+		r.resolveExpression(statement.Initialiser)
+	}
+	r.define(statement.Name)
+	return nil
+}
+
+func (r *Resolver) visitBlockStatement(block *Block) interface{} {
+	r.beginScope()
+	r.resolveStatements(block.Statements)
+	r.endScope()
+	return nil
+}
+
+func (r *Resolver) visitIFStatement(conditional *If) interface{} {
+	r.resolveExpression(conditional.Condition)
+	conditional.ThenBranch.Accept(r)
+	if conditional.ElseBranch != nil {
+		conditional.ElseBranch.Accept(r)
+	}
+	return nil
+}
+
+// visitImportStatement only allows imports at the top level, a module is run once so
+// binding it in a block that runs many times would be misleading
+func (r *Resolver) visitImportStatement(statement *Import) interface{} {
+	if len(r.scopes) > 0 {
+		r.error(statement.Keyword, "Modules can only be imported at the top level of a script.")
+	}
+	r.declare(statement.Name, false)
+	r.define(statement.Name)
+
+	// a module that can't be loaded is reported when the import runs
+	delete(r.modules, statement.Name.Lexeme)
+	written, _ := statement.Path.Literal.(string)
+	if module := NativeModule(written); module != nil {
+		if len(r.scopes) == 0 {
+			r.modules[statement.Name.Lexeme] = module.Members
+		}
+	} else if r.loader != nil && len(r.scopes) == 0 {
+		if path, err := r.loader.Resolve(written, r.path); err == nil {
+			if statements, err := r.loader.Load(path); err == nil {
+				r.modules[statement.Name.Lexeme] = Members(statements)
+			}
+		}
+	}
+	return nil
+}
+
+// visitGetExpression checks str.name against the declarations of the module str is bound to,
+// and the methods of string literals
+func (r *Resolver) visitGetExpression(get *Get) interface{} {
+	r.resolveExpression(get.Object)
+
+	if literal, ok := get.Object.(*Literal); ok {
+		if _, text := literal.value.(string); text {
+			if _, found := stringMethods[get.Name.Lexeme]; !found {
+				r.error(get.Name, "Strings have no method '"+get.Name.Lexeme+"'.")
+			}
+		}
+		return nil
+	}
+	variable, ok := get.Object.(*Variable)
+	if !ok || r.isLocal(variable.Name) {
+		return nil
+	}
+	members, found := r.modules[variable.Name.Lexeme]
+	if !found {
+		return nil
+	}
+	if exported, declared := members[get.Name.Lexeme]; !declared {
+		r.error(get.Name, "Module '"+variable.Name.Lexeme+"' has no member '"+get.Name.Lexeme+"'.")
+	} else if !exported {
+		r.error(get.Name, "'"+get.Name.Lexeme+"' is not exported by module '"+variable.Name.Lexeme+"'.")
+	}
+	return nil
+}
+
+// isLocal reports whether the name refers to a variable declared in a block
+func (r *Resolver) isLocal(name Token) bool {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if _, found := r.scopes[i][name.Lexeme]; found {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Resolver) visitCallExpression(call *Call) interface{} {
+	r.resolveExpression(call.Callee)
+	for _, argument := range call.Arguments {
+		r.resolveExpression(argument)
+	}
+	return nil
+}
+
+func (r *Resolver) visitBinaryExpression(binary *Binary) interface{} {
+	r.resolveExpression(binary.left)
+	r.resolveExpression(binary.right)
+	return nil
+}
+
+func (r *Resolver) visitGroupingExpression(grouping *Grouping) interface{} {
+	r.resolveExpression(grouping.expression)
+	return nil
+}
+
+func (r *Resolver) visitLiteralExpression(literal *Literal) interface{} {
+	return nil
+}
+
+func (r *Resolver) visitUnaryExpression(unary *Unary) interface{} {
+	r.resolveExpression(unary.right)
+	return nil
+}
+
+func (r *Resolver) visitVariableDeclarationExpression(variable *Variable) interface{} {
+	if len(r.scopes) > 0 {
+		if defined, found := r.scopes[len(r.scopes)-1][variable.Name.Lexeme]; found && !defined {
+			r.error(variable.Name, "Can't read local variable in its own initializer.")
+		}
+	}
+	return nil
+}
+
+func (r *Resolver) visitLogicalExpression(logical *Logical) interface{} {
+	r.resolveExpression(logical.left)
+	r.resolveExpression(logical.right)
+	return nil
+}
+
+func (r *Resolver) visitConditionalExpression(conditional *Conditional) interface{} {
+	r.resolveExpression(conditional.condition)
+	r.resolveExpression(conditional.thenBranch)
+	r.resolveExpression(conditional.elseBranch)
+	return nil
+}
+
+func (r *Resolver) visitAssignmentExpression(assignment *Assignment) interface{} {
+	r.resolveExpression(assignment.Value)
+	if r.isConstant(assignment.Name) {
+		r.error(assignment.Name, "Can't assign to constant '"+assignment.Name.Lexeme+"'.")
+	}
+	return nil
+}
+
+func (r *Resolver) visitUpdateExpression(update *Update) interface{} {
+	if r.isConstant(update.Name) {
+		r.error(update.Name, "Can't assign to constant '"+update.Name.Lexeme+"'.")
+	}
+	return nil
+}
+
+// isConstant finds the declaration the name refers to at this point and whether it is a constant
+func (r *Resolver) isConstant(name Token) bool {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if _, found := r.scopes[i][name.Lexeme]; found {
+			return r.constants[i][name.Lexeme]
+		}
+	}
+	return r.globalConstants[name.Lexeme]
+}
@@ -0,0 +1,47 @@
+package semantics_test
+
+import (
+	"scoop/components"
+	"scoop/semantics"
+	"testing"
+)
+
+// the mistakes scoop check reports, the README lists them
+func TestResolverErrors(t *testing.T) {
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{`{ var a = 1; var a = 2; }`, "Already a variable with this name in this scope."},
+		{`{ var a = a; }`, "Can't read local variable in its own initializer."},
+		{`const a = 1; var a = 2;`, "Can't redeclare constant 'a'."},
+		{`const a = 1; a = 2;`, "Can't assign to constant 'a'."},
+		{`{ const a = 1; a += 2; }`, "Can't assign to constant 'a'."},
+		{`const a = 1; a++;`, "Can't assign to constant 'a'."},
+		{`{ export var a = 1; }`, "Only top level declarations can be exported."},
+		{`{ import "math" as math; }`, "Modules can only be imported at the top level of a script."},
+		{`import "math" as math; print math.nothing;`, "Module 'math' has no member 'nothing'."},
+		{`print "text".nothing();`, "Strings have no method 'nothing'."},
+	}
+	for _, test := range tests {
+		statements, err := components.InitParser(components.InitScanner(test.source).ScanTokens()).Parse()
+		if err != nil {
+			t.Fatalf("parse %q: %v", test.source, err)
+		}
+		errs := semantics.InitResolver().Resolve(statements)
+		if len(errs) != 1 {
+			t.Errorf("%v: want one error, got %v", test.source, errs)
+			continue
+		}
+		if resolveErr, ok := errs[0].(*semantics.ResolveError); !ok || resolveErr.Message != test.want {
+			t.Errorf("%v: got %v, want %q", test.source, errs[0], test.want)
+		}
+	}
+
+	for _, source := range []string{`var a = 1; var a = 2;`, `var a = 1; { var b = a; }`, `{ var a = 1; { var a = 2; } }`} {
+		statements, _ := components.InitParser(components.InitScanner(source).ScanTokens()).Parse()
+		if errs := semantics.InitResolver().Resolve(statements); len(errs) != 0 {
+			t.Errorf("%v: want no error, got %v", source, errs)
+		}
+	}
+}
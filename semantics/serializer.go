@@ -0,0 +1,352 @@
+package semantics
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// SyntaxNode is the JSON shape of every Statement and Expression.
+// Kind names the Go type of the node ("Binary", "Var", ...), only the fields
+// that node has are filled in, so nothing is lost going through JSON and back
+type SyntaxNode struct {
+	Kind        string        `json:"kind"`
+	Keyword     *SyntaxToken  `json:"keyword,omitempty"`
+	Name        *SyntaxToken  `json:"name,omitempty"`
+	Operator    *SyntaxToken  `json:"operator,omitempty"`
+	Literal     interface{}   `json:"literal,omitempty"`
+	Left        *SyntaxNode   `json:"left,omitempty"`
+	Right       *SyntaxNode   `json:"right,omitempty"`
+	Expression  *SyntaxNode   `json:"expression,omitempty"`
+	Value       *SyntaxNode   `json:"value,omitempty"`
+	Initialiser *SyntaxNode   `json:"initialiser,omitempty"`
+	Condition   *SyntaxNode   `json:"condition,omitempty"`
+	ThenBranch  *SyntaxNode   `json:"then,omitempty"`
+	ElseBranch  *SyntaxNode   `json:"else,omitempty"`
+	Statements  []*SyntaxNode `json:"statements,omitempty"`
+	Constant    bool          `json:"constant,omitempty"`
+	Prefix      bool          `json:"prefix,omitempty"`
+	Exported    bool          `json:"exported,omitempty"`
+	Doc         string        `json:"doc,omitempty"`
+	Path        *SyntaxToken  `json:"path,omitempty"`
+	Object      *SyntaxNode   `json:"object,omitempty"`
+	Callee      *SyntaxNode   `json:"callee,omitempty"`
+	Paren       *SyntaxToken  `json:"paren,omitempty"`
+	Arguments   []*SyntaxNode `json:"arguments,omitempty"`
+	Values      []*SyntaxNode `json:"values,omitempty"`
+	// where a statement starts, expressions carry their positions in their tokens
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+}
+
+type SyntaxToken struct {
+	Type    string      `json:"type"`
+	Lexeme  string      `json:"lexeme"`
+	Literal interface{} `json:"literal,omitempty"`
+	Line    int         `json:"line"`
+	Column  int         `json:"column"`
+}
+
+// JSONEncoder turns a program into SyntaxNodes and then JSON
+type JSONEncoder struct {
+}
+
+func InitJSONEncoder() *JSONEncoder {
+	return &JSONEncoder{}
+}
+
+// Encode renders the program as {"kind": "Program", "statements": [...]}
+func (j *JSONEncoder) Encode(statements []Statement) ([]byte, error) {
+	return json.MarshalIndent(j.Program(statements), "", "  ")
+}
+
+func (j *JSONEncoder) Program(statements []Statement) *SyntaxNode {
+	return &SyntaxNode{Kind: "Program", Statements: j.statements(statements)}
+}
+
+func (j *JSONEncoder) statements(statements []Statement) []*SyntaxNode {
+	nodes := []*SyntaxNode{}
+	for _, statement := range statements {
+		nodes = append(nodes, j.statement(statement))
+	}
+	return nodes
+}
+
+func (j *JSONEncoder) statement(statement Statement) *SyntaxNode {
+	if statement == nil {
+		return nil
+	}
+	node := statement.Accept(j).(*SyntaxNode)
+	node.Line = statement.Start().Line
+	node.Column = statement.Start().Column
+	return node
+}
+
+func (j *JSONEncoder) expression(expression Expression) *SyntaxNode {
+	if expression == nil {
+		return nil
+	}
+	return expression.Accept(j).(*SyntaxNode)
+}
+
+func (j *JSONEncoder) token(token Token) *SyntaxToken {
+	return &SyntaxToken{Type: token.TokenType.String(), Lexeme: token.Lexeme, Literal: token.Literal, Line: token.Line, Column: token.Column}
+}
+
+func (j *JSONEncoder) visitExpressionStatement(statement *ExpressionStatement) interface{} {
+	return &SyntaxNode{Kind: "ExpressionStatement", Expression: j.expression(statement.Expr)}
+}
+
+func (j *JSONEncoder) visitPrintStatement(statement *Print) interface{} {
+	values := []*SyntaxNode{}
+	for _, value := range statement.Values {
+		values = append(values, j.expression(value))
+	}
+	return &SyntaxNode{Kind: "Print", Values: values}
+}
+
+func (j *JSONEncoder) visitVariableDeclarationStatement(statement *Var) interface{} {
+	return &SyntaxNode{Kind: "Var", Name: j.token(statement.Name), Initialiser: j.expression(statement.Initialiser), Constant: statement.Constant, Exported: statement.Exported, Doc: statement.Comment}
+}
+
+func (j *JSONEncoder) visitBlockStatement(block *Block) interface{} {
+	return &SyntaxNode{Kind: "Block", Statements: j.statements(block.Statements)}
+}
+
+func (j *JSONEncoder) visitIFStatement(conditional *If) interface{} {
+	return &SyntaxNode{
+		Kind:       "If",
+		Keyword:    j.token(conditional.Keyword),
+		Condition:  j.expression(conditional.Condition),
+		ThenBranch: j.statement(conditional.ThenBranch),
+		ElseBranch: j.statement(conditional.ElseBranch),
+	}
+}
+
+func (j *JSONEncoder) visitImportStatement(statement *Import) interface{} {
+	return &SyntaxNode{Kind: "Import", Keyword: j.token(statement.Keyword), Path: j.token(statement.Path), Name: j.token(statement.Name)}
+}
+
+func (j *JSONEncoder) visitGetExpression(get *Get) interface{} {
+	return &SyntaxNode{Kind: "Get", Object: j.expression(get.Object), Operator: j.token(get.Operator), Name: j.token(get.Name)}
+}
+
+func (j *JSONEncoder) visitCallExpression(call *Call) interface{} {
+	arguments := []*SyntaxNode{}
+	for _, argument := range call.Arguments {
+		arguments = append(arguments, j.expression(argument))
+	}
+	return &SyntaxNode{Kind: "Call", Callee: j.expression(call.Callee), Paren: j.token(call.Paren), Arguments: arguments}
+}
+
+func (j *JSONEncoder) visitBinaryExpression(binary *Binary) interface{} {
+	return &SyntaxNode{Kind: "Binary", Left: j.expression(binary.left), Operator: j.token(binary.operator), Right: j.expression(binary.right)}
+}
+
+func (j *JSONEncoder) visitGroupingExpression(grouping *Grouping) interface{} {
+	return &SyntaxNode{Kind: "Grouping", Expression: j.expression(grouping.expression)}
+}
+
+func (j *JSONEncoder) visitLiteralExpression(literal *Literal) interface{} {
+	return &SyntaxNode{Kind: "Literal", Literal: literal.value}
+}
+
+func (j *JSONEncoder) visitUnaryExpression(unary *Unary) interface{} {
+	return &SyntaxNode{Kind: "Unary", Operator: j.token(unary.operator), Right: j.expression(unary.right)}
+}
+
+func (j *JSONEncoder) visitVariableDeclarationExpression(variable *Variable) interface{} {
+	return &SyntaxNode{Kind: "Variable", Name: j.token(variable.Name)}
+}
+
+func (j *JSONEncoder) visitLogicalExpression(logical *Logical) interface{} {
+	return &SyntaxNode{Kind: "Logical", Left: j.expression(logical.left), Operator: j.token(logical.operator), Right: j.expression(logical.right)}
+}
+
+func (j *JSONEncoder) visitConditionalExpression(conditional *Conditional) interface{} {
+	return &SyntaxNode{
+		Kind:       "Conditional",
+		Keyword:    j.token(conditional.question),
+		Condition:  j.expression(conditional.condition),
+		ThenBranch: j.expression(conditional.thenBranch),
+		ElseBranch: j.expression(conditional.elseBranch),
+	}
+}
+
+func (j *JSONEncoder) visitAssignmentExpression(assignment *Assignment) interface{} {
+	node := &SyntaxNode{Kind: "Assignment", Name: j.token(assignment.Name), Value: j.expression(assignment.Value)}
+	if assignment.IsCompound() {
+		node.Operator = j.token(assignment.Operator)
+	}
+	return node
+}
+
+func (j *JSONEncoder) visitUpdateExpression(update *Update) interface{} {
+	return &SyntaxNode{Kind: "Update", Name: j.token(update.Name), Operator: j.token(update.Operator), Prefix: update.Prefix}
+}
+
+// JSONDecoder rebuilds executable Statements from the JSON written by JSONEncoder
+type JSONDecoder struct {
+}
+
+func InitJSONDecoder() *JSONDecoder {
+	return &JSONDecoder{}
+}
+
+func (j *JSONDecoder) Decode(data []byte) ([]Statement, error) {
+	program := &SyntaxNode{}
+	if err := json.Unmarshal(data, program); err != nil {
+		return nil, err
+	}
+	return j.Program(program)
+}
+
+// Program rebuilds the statements of a "Program" node, any malformed node is reported as an error
+func (j *JSONDecoder) Program(program *SyntaxNode) (statements []Statement, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			if decodeErr, ok := recovered.(*decodeError); ok {
+				statements, err = nil, decodeErr
+				return
+			}
+			panic(recovered)
+		}
+	}()
+
+	if program.Kind != "Program" {
+		return nil, &decodeError{message: fmt.Sprintf("expected a Program node, found '%v'", program.Kind)}
+	}
+	return j.statements(program.Statements), nil
+}
+
+type decodeError struct {
+	message string
+}
+
+func (e *decodeError) Error() string {
+	return "Invalid syntax tree: " + e.message
+}
+
+func (j *JSONDecoder) error(node *SyntaxNode, message string) error {
+	return &decodeError{message: fmt.Sprintf("%v node %v", node.Kind, message)}
+}
+
+func (j *JSONDecoder) statements(nodes []*SyntaxNode) []Statement {
+	statements := []Statement{}
+	for _, node := range nodes {
+		statements = append(statements, j.requireStatement(node, "statement"))
+	}
+	return statements
+}
+
+func (j *JSONDecoder) requireStatement(node *SyntaxNode, field string) Statement {
+	if node == nil {
+		panic(&decodeError{message: "missing " + field})
+	}
+	return j.statement(node)
+}
+
+func (j *JSONDecoder) requireExpression(node *SyntaxNode, field string) Expression {
+	if node == nil {
+		panic(&decodeError{message: "missing " + field})
+	}
+	return j.expression(node)
+}
+
+func (j *JSONDecoder) optionalExpression(node *SyntaxNode) Expression {
+	if node == nil {
+		return nil
+	}
+	return j.expression(node)
+}
+
+func (j *JSONDecoder) statement(node *SyntaxNode) Statement {
+	statement := j.buildStatement(node)
+	if documented, ok := statement.(Documented); ok {
+		documented.SetDocComment(node.Doc)
+	}
+	statement.Start().Line = node.Line
+	statement.Start().Column = node.Column
+	return statement
+}
+
+func (j *JSONDecoder) buildStatement(node *SyntaxNode) Statement {
+	switch node.Kind {
+	case "ExpressionStatement":
+		return InitExpressionStatement(j.requireExpression(node.Expression, "expression"))
+	case "Print":
+		if len(node.Values) == 0 {
+			panic(j.error(node, "has no values"))
+		}
+		values := []Expression{}
+		for _, value := range node.Values {
+			values = append(values, j.requireExpression(value, "value"))
+		}
+		return InitPrintStatement(values...)
+	case "Var":
+		var declaration *Var
+		if node.Constant {
+			declaration = InitConstantDeclaration(j.token(node, node.Name), j.requireExpression(node.Initialiser, "initialiser"))
+		} else {
+			declaration = InitVariableDeclaration(j.token(node, node.Name), j.optionalExpression(node.Initialiser))
+		}
+		declaration.Exported = node.Exported
+		return declaration
+	case "Block":
+		return InitBlockStatement(j.statements(node.Statements))
+	case "If":
+		var elseBranch Statement
+		if node.ElseBranch != nil {
+			elseBranch = j.statement(node.ElseBranch)
+		}
+		return InitIFStatement(j.token(node, node.Keyword), j.requireExpression(node.Condition, "condition"), j.requireStatement(node.ThenBranch, "then"), elseBranch)
+	case "Import":
+		return InitImportStatement(j.token(node, node.Keyword), j.token(node, node.Path), j.token(node, node.Name))
+	}
+	panic(j.error(node, "is not a statement"))
+}
+
+func (j *JSONDecoder) expression(node *SyntaxNode) Expression {
+	switch node.Kind {
+	case "Binary":
+		return InitBinary(j.requireExpression(node.Left, "left"), j.token(node, node.Operator), j.requireExpression(node.Right, "right"))
+	case "Grouping":
+		return InitGrouping(j.requireExpression(node.Expression, "expression"))
+	case "Literal":
+		return InitLiteral(node.Literal)
+	case "Unary":
+		return InitUnary(j.token(node, node.Operator), j.requireExpression(node.Right, "right"))
+	case "Variable":
+		return InitVariable(j.token(node, node.Name))
+	case "Assignment":
+		if node.Operator != nil {
+			return InitCompoundAssignment(j.token(node, node.Name), j.token(node, node.Operator), j.requireExpression(node.Value, "value"))
+		}
+		return InitAssignment(j.token(node, node.Name), j.requireExpression(node.Value, "value"))
+	case "Update":
+		return InitUpdate(j.token(node, node.Name), j.token(node, node.Operator), node.Prefix)
+	case "Logical":
+		return InitLogical(j.requireExpression(node.Left, "left"), j.token(node, node.Operator), j.requireExpression(node.Right, "right"))
+	case "Get":
+		return InitGet(j.requireExpression(node.Object, "object"), j.token(node, node.Operator), j.token(node, node.Name))
+	case "Call":
+		arguments := []Expression{}
+		for _, argument := range node.Arguments {
+			arguments = append(arguments, j.requireExpression(argument, "argument"))
+		}
+		return InitCall(j.requireExpression(node.Callee, "callee"), j.token(node, node.Paren), arguments)
+	case "Conditional":
+		return InitConditional(j.requireExpression(node.Condition, "condition"), j.token(node, node.Keyword), j.requireExpression(node.ThenBranch, "then"), j.requireExpression(node.ElseBranch, "else"))
+	}
+	panic(j.error(node, "is not an expression"))
+}
+
+func (j *JSONDecoder) token(node *SyntaxNode, token *SyntaxToken) Token {
+	if token == nil {
+		panic(j.error(node, "is missing a token"))
+	}
+	tokenType, ok := TokenTypeFromString(token.Type)
+	if !ok {
+		panic(j.error(node, "has unknown token type '"+token.Type+"'"))
+	}
+	return Token{TokenType: tokenType, Lexeme: token.Lexeme, Literal: token.Literal, Line: token.Line, Column: token.Column}
+}
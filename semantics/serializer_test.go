@@ -0,0 +1,74 @@
+package semantics_test
+
+import (
+	"scoop/components"
+	"scoop/semantics"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	source := `/// the answer
+export const answer = 42;
+var a = 1;
+import "math" as math;
+{
+  var b = -a + 2 * (3 - a) ** 2;
+  a += b;
+  b++;
+  --b;
+}
+if (a > 1 == !false) print a, "big"; else if (a == 1) print "one"; else print nil;
+print a ?? 0, a > 0 ? "yes" : "no", math.sqrt(16), format("{}-{}", a, "x".upper()), math?.pi;
+`
+	statements, err := components.InitParser(components.InitScanner(source).ScanTokens()).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := semantics.InitJSONEncoder().Encode(statements)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := semantics.InitJSONDecoder().Decode(encoded)
+	if err != nil {
+		t.Fatalf("decoding %s: %v", encoded, err)
+	}
+
+	printer := semantics.InitAbstractSyntaxTreePrinter()
+	if got, want := printer.PrintProgram(decoded), printer.PrintProgram(statements); got != want {
+		t.Errorf("the decoded program differs\n got:\n%v\nwant:\n%v", got, want)
+	}
+	reencoded, err := semantics.InitJSONEncoder().Encode(decoded)
+	if err != nil || string(reencoded) != string(encoded) {
+		t.Errorf("encoding the decoded program gave different JSON: %v\n%s", err, reencoded)
+	}
+}
+
+func TestJSONDecoderErrors(t *testing.T) {
+	literal := `{"kind": "Literal", "literal": 1}`
+	paren := `"paren": {"type": "RIGHT_PAREN", "lexeme": ")", "line": 1, "column": 5}`
+	callee := `"callee": {"kind": "Variable", "name": {"type": "IDENTIFIER", "lexeme": "format", "line": 1, "column": 0}}`
+	program := func(statement string) string {
+		return `{"kind": "Program", "statements": [` + statement + `]}`
+	}
+	tests := []struct {
+		name string
+		json string
+		want string
+	}{
+		{"null value", program(`{"kind": "Print", "values": [` + literal + `, null]}`), "missing value"},
+		{"missing values", program(`{"kind": "Print"}`), "has no values"},
+		{"statement as a value", program(`{"kind": "Print", "values": [{"kind": "Block"}]}`), "Block node is not an expression"},
+		{"null argument", program(`{"kind": "ExpressionStatement", "expression": {"kind": "Call", ` + callee + `, ` + paren + `, "arguments": [null]}}`), "missing argument"},
+		{"statement as an argument", program(`{"kind": "ExpressionStatement", "expression": {"kind": "Call", ` + callee + `, ` + paren + `, "arguments": [{"kind": "Print"}]}}`), "Print node is not an expression"},
+		{"missing callee", program(`{"kind": "ExpressionStatement", "expression": {"kind": "Call", ` + paren + `, "arguments": []}}`), "missing callee"},
+		{"null statement", program(`null`), "missing statement"},
+		{"not a program", `{"kind": "Block"}`, "expected a Program node"},
+	}
+	for _, test := range tests {
+		_, err := semantics.InitJSONDecoder().Decode([]byte(test.json))
+		if err == nil || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("%v: want an error mentioning %q, got %v", test.name, test.want, err)
+		}
+	}
+}
@@ -10,27 +10,63 @@ type StatementVisitor interface {
 	visitBlockStatement(block *Block) interface{}
 
 	visitIFStatement(conditional *If) interface{}
+
+	visitImportStatement(statement *Import) interface{}
 }
 
 type Statement interface {
 	Accept(visitor StatementVisitor) interface{}
+	Start() *Span
+}
+
+// Span is where a statement starts in the source, the parser fills it in.
+// Every statement embeds one so tools (e.g. the debugger) can map statements to lines
+type Span struct {
+	Line   int
+	Column int
+}
+
+func (s *Span) Start() *Span {
+	return s
+}
+
+// Docs holds the "///" comment written right above a declaration
+type Docs struct {
+	Comment string
 }
 
+func (d *Docs) DocComment() string {
+	return d.Comment
+}
+
+func (d *Docs) SetDocComment(comment string) {
+	d.Comment = comment
+}
+
+// Documented is implemented by every declaration a doc comment can be attached to
+type Documented interface {
+	DocComment() string
+	SetDocComment(comment string)
+}
+
+// Print writes its values on one line, separated by spaces
 type Print struct {
-	Expr Expression
+	Span
+	Values []Expression
 }
 
 func (p *Print) Accept(visitor StatementVisitor) interface{} {
 	return visitor.visitPrintStatement(p)
 }
 
-func InitPrintStatement(expr Expression) *Print {
+func InitPrintStatement(values ...Expression) *Print {
 	return &Print{
-		Expr: expr,
+		Values: values,
 	}
 }
 
 type ExpressionStatement struct {
+	Span
 	Expr Expression
 }
 
@@ -46,8 +82,14 @@ func InitExpressionStatement(expr Expression) *ExpressionStatement {
 
 // var declaration statement
 type Var struct {
+	Span
+	Docs
 	Name        Token
 	Initialiser Expression
+	// Constant is set for "const" declarations, which can't be assigned to
+	Constant bool
+	// Exported top level declarations are the only ones importers can read
+	Exported bool
 }
 
 func (v *Var) Accept(visitor StatementVisitor) interface{} {
@@ -61,6 +103,14 @@ func InitVariableDeclaration(token Token, expr Expression) *Var {
 	}
 }
 
+func InitConstantDeclaration(token Token, expr Expression) *Var {
+	return &Var{
+		Initialiser: expr,
+		Name:        token,
+		Constant:    true,
+	}
+}
+
 //Definittion
 // program        → statement* EOF ;
 
@@ -78,6 +128,7 @@ func InitVariableDeclaration(token Token, expr Expression) *Var {
 // block          → "{" declaration* "}" ;
 
 type Block struct {
+	Span
 	Statements []Statement
 }
 
@@ -92,6 +143,9 @@ func InitBlockStatement(states []Statement) *Block {
 }
 
 type If struct {
+	Span
+	// the 'if' token, it gives the statement a position in the source
+	Keyword    Token
 	Condition  Expression
 	ThenBranch Statement
 	ElseBranch Statement
@@ -101,10 +155,33 @@ func (i *If) Accept(visitor StatementVisitor) interface{} {
 	return visitor.visitIFStatement(i)
 }
 
-func InitIFStatement(condition Expression, thenBranch Statement, elseBranch Statement) *If {
+func InitIFStatement(keyword Token, condition Expression, thenBranch Statement, elseBranch Statement) *If {
 	return &If{
+		Keyword:    keyword,
 		Condition:  condition,
 		ThenBranch: thenBranch,
 		ElseBranch: elseBranch,
 	}
 }
+
+// Import runs another script once and binds its globals to Name as a module,
+// import "lib/strings.scoop" as str;
+type Import struct {
+	Span
+	Keyword Token
+	// the STRING token of the path, as written
+	Path Token
+	Name Token
+}
+
+func (i *Import) Accept(visitor StatementVisitor) interface{} {
+	return visitor.visitImportStatement(i)
+}
+
+func InitImportStatement(keyword Token, path Token, name Token) *Import {
+	return &Import{
+		Keyword: keyword,
+		Path:    path,
+		Name:    name,
+	}
+}
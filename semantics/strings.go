@@ -0,0 +1,141 @@
+package semantics
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// stringMethod is a method of string values, "text".upper(). Positions and lengths count
+// characters (runes) rather than bytes so they hold for any Unicode text
+type stringMethod struct {
+	arity    int
+	optional int
+	function func(text string, call *NativeCall) interface{}
+}
+
+var stringMethods = map[string]stringMethod{
+	"length": {0, 0, func(text string, call *NativeCall) interface{} {
+		return float64(utf8.RuneCountInString(text))
+	}},
+	// substring(start, end) is the characters from start up to but not including end,
+	// the end of the string without end
+	"substring": {1, 1, func(text string, call *NativeCall) interface{} {
+		characters := []rune(text)
+		start, end := call.Integer(0), len(characters)
+		if call.Has(1) {
+			end = call.Integer(1)
+		}
+		if start < 0 || end > len(characters) || start > end {
+			call.Error("string.substring(" + call.Stringify(float64(start)) + ", " + call.Stringify(float64(end)) + ") is out of range for a string of " + call.Stringify(float64(len(characters))) + " characters.")
+		}
+		return string(characters[start:end])
+	}},
+	"indexOf": {1, 0, func(text string, call *NativeCall) interface{} {
+		index := strings.Index(text, call.String(0))
+		if index < 0 {
+			return float64(-1)
+		}
+		return float64(utf8.RuneCountInString(text[:index]))
+	}},
+	"contains": {1, 0, func(text string, call *NativeCall) interface{} {
+		return strings.Contains(text, call.String(0))
+	}},
+	// split("") splits the string into its characters
+	"split": {1, 0, func(text string, call *NativeCall) interface{} {
+		elements := []interface{}{}
+		for _, part := range strings.Split(text, call.String(0)) {
+			elements = append(elements, part)
+		}
+		return InitList(elements)
+	}},
+	// join puts the string between the elements of a list, ", ".join(args)
+	"join": {1, 0, func(text string, call *NativeCall) interface{} {
+		parts := []string{}
+		length := 0
+		for _, element := range call.List(0).Elements {
+			part := call.Stringify(element)
+			parts = append(parts, part)
+			length += len(part) + len(text)
+		}
+		call.CheckStringLength(length)
+		return strings.Join(parts, text)
+	}},
+	"trim": {0, 0, func(text string, call *NativeCall) interface{} {
+		return strings.TrimSpace(text)
+	}},
+	"trimStart": {0, 0, func(text string, call *NativeCall) interface{} {
+		return strings.TrimLeftFunc(text, unicode.IsSpace)
+	}},
+	"trimEnd": {0, 0, func(text string, call *NativeCall) interface{} {
+		return strings.TrimRightFunc(text, unicode.IsSpace)
+	}},
+	"upper": {0, 0, func(text string, call *NativeCall) interface{} {
+		return strings.ToUpper(text)
+	}},
+	"lower": {0, 0, func(text string, call *NativeCall) interface{} {
+		return strings.ToLower(text)
+	}},
+	// replace changes every occurrence of old
+	"replace": {2, 0, func(text string, call *NativeCall) interface{} {
+		old, replacement := call.String(0), call.String(1)
+		if count := strings.Count(text, old); count > 0 {
+			call.CheckStringLength(len(text) + count*(len(replacement)-len(old)))
+		}
+		return strings.ReplaceAll(text, old, replacement)
+	}},
+	"startsWith": {1, 0, func(text string, call *NativeCall) interface{} {
+		return strings.HasPrefix(text, call.String(0))
+	}},
+	"endsWith": {1, 0, func(text string, call *NativeCall) interface{} {
+		return strings.HasSuffix(text, call.String(0))
+	}},
+	"repeat": {1, 0, func(text string, call *NativeCall) interface{} {
+		count := call.Integer(0)
+		if count < 0 {
+			call.Error("string.repeat can't repeat a string a negative number of times.")
+		}
+		call.CheckStringLength(len(text) * count)
+		return strings.Repeat(text, count)
+	}},
+	// padStart(width, fill) adds fill (a space without it) in front until the string is
+	// width characters long, padEnd adds it at the end
+	"padStart": {1, 1, func(text string, call *NativeCall) interface{} {
+		return padding(text, call) + text
+	}},
+	"padEnd": {1, 1, func(text string, call *NativeCall) interface{} {
+		return text + padding(text, call)
+	}},
+	"reverse": {0, 0, func(text string, call *NativeCall) interface{} {
+		characters := []rune(text)
+		for i, j := 0, len(characters)-1; i < j; i, j = i+1, j-1 {
+			characters[i], characters[j] = characters[j], characters[i]
+		}
+		return string(characters)
+	}},
+}
+
+// padding is the fill a string needs to be as wide as the first argument of the call
+func padding(text string, call *NativeCall) string {
+	width, fill := call.Integer(0), " "
+	if call.Has(1) {
+		fill = call.String(1)
+	}
+	missing := width - utf8.RuneCountInString(text)
+	if missing <= 0 || fill == "" {
+		return ""
+	}
+	call.CheckStringLength(len(text) + missing*len(fill))
+	return string([]rune(strings.Repeat(fill, missing))[:missing])
+}
+
+// stringMember returns the method of the string the name refers to, bound to the string
+func (p *Interpreter) stringMember(text string, name Token) *Native {
+	method, found := stringMethods[name.Lexeme]
+	if !found {
+		panic(p.error(name, "Strings have no method '"+name.Lexeme+"'."))
+	}
+	return &Native{Name: "string." + name.Lexeme, Arity: method.arity, Optional: method.optional, Function: func(call *NativeCall) interface{} {
+		return method.function(text, call)
+	}}
+}
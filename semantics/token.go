@@ -19,6 +19,13 @@ const (
 	SEMICOLON
 	SLASH
 	STAR
+	QUESTION
+	COLON
+	PERCENT
+	AMPERSAND
+	PIPE
+	CARET
+	TILDE
 
 	//ONE OR TWO CHARACTER TOKENS
 	BANG
@@ -29,10 +36,26 @@ const (
 	GREATER_EQUAL
 	LESS
 	LESS_EQUAL
+	QUESTION_QUESTION
+	QUESTION_DOT
+	PLUS_EQUAL
+	MINUS_EQUAL
+	STAR_EQUAL
+	SLASH_EQUAL
+	PLUS_PLUS
+	MINUS_MINUS
+	STAR_STAR
+	LESS_LESS
+	GREATER_GREATER
 	IDENTIFIER
 	STRING
 	NUMBER
 
+	//TRIVIA, only produced when the scanner preserves trivia
+	COMMENT
+	//DOC_COMMENT is a "///" comment, always produced so the parser can attach it to a declaration
+	DOC_COMMENT
+
 	//KEYWORDS
 	AND
 	CLASS
@@ -50,13 +73,108 @@ const (
 	TRUE
 	VAR
 	WHILE
+	CONST
+	IMPORT
+	AS
+	EXPORT
 )
 
+var tokenTypeNames = [...]string{
+	EOF:               "EOF",
+	LEFT_PAREN:        "LEFT_PAREN",
+	RIGHT_PAREN:       "RIGHT_PAREN",
+	LEFT_BRACE:        "LEFT_BRACE",
+	RIGHT_BRACE:       "RIGHT_BRACE",
+	COMMA:             "COMMA",
+	DOT:               "DOT",
+	PLUS:              "PLUS",
+	MINUS:             "MINUS",
+	SEMICOLON:         "SEMICOLON",
+	SLASH:             "SLASH",
+	STAR:              "STAR",
+	QUESTION:          "QUESTION",
+	COLON:             "COLON",
+	PERCENT:           "PERCENT",
+	AMPERSAND:         "AMPERSAND",
+	PIPE:              "PIPE",
+	CARET:             "CARET",
+	TILDE:             "TILDE",
+	BANG:              "BANG",
+	BANG_EQUAL:        "BANG_EQUAL",
+	EQUAL:             "EQUAL",
+	EQUAL_EQUAL:       "EQUAL_EQUAL",
+	GREATER:           "GREATER",
+	GREATER_EQUAL:     "GREATER_EQUAL",
+	LESS:              "LESS",
+	LESS_EQUAL:        "LESS_EQUAL",
+	QUESTION_QUESTION: "QUESTION_QUESTION",
+	QUESTION_DOT:      "QUESTION_DOT",
+	PLUS_EQUAL:        "PLUS_EQUAL",
+	MINUS_EQUAL:       "MINUS_EQUAL",
+	STAR_EQUAL:        "STAR_EQUAL",
+	SLASH_EQUAL:       "SLASH_EQUAL",
+	PLUS_PLUS:         "PLUS_PLUS",
+	MINUS_MINUS:       "MINUS_MINUS",
+	STAR_STAR:         "STAR_STAR",
+	LESS_LESS:         "LESS_LESS",
+	GREATER_GREATER:   "GREATER_GREATER",
+	IDENTIFIER:        "IDENTIFIER",
+	STRING:            "STRING",
+	NUMBER:            "NUMBER",
+	COMMENT:           "COMMENT",
+	DOC_COMMENT:       "DOC_COMMENT",
+	AND:               "AND",
+	CLASS:             "CLASS",
+	FALSE:             "FALSE",
+	FUN:               "FUN",
+	FOR:               "FOR",
+	IF:                "IF",
+	ELSE:              "ELSE",
+	NIL:               "NIL",
+	OR:                "OR",
+	PRINT:             "PRINT",
+	RETURN:            "RETURN",
+	SUPER:             "SUPER",
+	THIS:              "THIS",
+	TRUE:              "TRUE",
+	VAR:               "VAR",
+	WHILE:             "WHILE",
+	CONST:             "CONST",
+	IMPORT:            "IMPORT",
+	AS:                "AS",
+	EXPORT:            "EXPORT",
+}
+
+// IsComment reports whether the token is a comment rather than code
+func (t TokenType) IsComment() bool {
+	return t == COMMENT || t == DOC_COMMENT
+}
+
+func (t TokenType) String() string {
+	if t < 0 || int(t) >= len(tokenTypeNames) {
+		return fmt.Sprintf("TokenType(%d)", int(t))
+	}
+	return tokenTypeNames[t]
+}
+
+// TokenTypeFromString is the reverse of TokenType.String
+func TokenTypeFromString(name string) (TokenType, bool) {
+	for tokenType, tokenName := range tokenTypeNames {
+		if tokenName == name {
+			return TokenType(tokenType), true
+		}
+	}
+	return EOF, false
+}
+
 type Token struct {
 	TokenType TokenType
 	Lexeme    string
 	Literal   interface{}
-	Line      int
+	// Line is where the lexeme starts and Column is the byte offset of its
+	// first character in that line, both as written in the source
+	Line   int
+	Column int
 }
 
 func (t *Token) toString() {
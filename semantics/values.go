@@ -0,0 +1,21 @@
+package semantics
+
+import "strings"
+
+// List is an ordered collection of runtime values, it is how the host hands
+// several values to a script (e.g. the command line args)
+type List struct {
+	Elements []interface{}
+}
+
+func InitList(elements []interface{}) *List {
+	return &List{Elements: elements}
+}
+
+func (p *Interpreter) stringifyList(list *List) string {
+	parts := []string{}
+	for _, element := range list.Elements {
+		parts = append(parts, p.stringify(element))
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}